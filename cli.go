@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var version = "dev"
+
+type cliOptions struct {
+	path           string
+	configPath     string
+	showHidden     bool
+	filter         string
+	query          string
+	sort           string
+	cdFile         string
+	selectionFile  string
+	format         string
+	stdoutFormat   string
+	importFrecency string
+	version        bool
+	set            map[string]bool
+}
+
+func parseFlags(args []string, output io.Writer) (cliOptions, error) {
+	var opts cliOptions
+	fs := flag.NewFlagSet("nav", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&opts.configPath, "config", "", "read the config from `file` instead of the default location")
+	fs.BoolVar(&opts.showHidden, "hidden", false, "show hidden files")
+	fs.StringVar(&opts.filter, "filter", "", "filter `mode`: "+strings.Join(filterKindNames, ", "))
+	fs.StringVar(&opts.query, "query", "", "start with the filter applied to `text`")
+	fs.StringVar(&opts.sort, "sort", "", "sort `mode`: "+strings.Join(sortModeNames, ", "))
+	fs.StringVar(&opts.cdFile, "cd-file", "", "write the last directory to `file` on exit")
+	fs.StringVar(&opts.selectionFile, "selection-file", "", "write the selected paths to `file` on exit")
+	fs.StringVar(&opts.format, "selection-format", "", "`format` of the selection file: "+strings.Join(outputKindNames, ", ")+", optionally followed by ,relative")
+	fs.StringVar(&opts.stdoutFormat, "print-selection", "", "print the selection to stdout on exit in `format`")
+	fs.StringVar(&opts.importFrecency, "import-frecency", "", "import a zoxide, z or autojump database from `file` and exit")
+	fs.BoolVar(&opts.version, "version", false, "print the version and exit")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: nav [flags] [directory or file]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		opts.path = fs.Arg(0)
+	default:
+		err := errors.New("too many arguments")
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return opts, err
+	}
+	opts.set = make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		opts.set[f.Name] = true
+	})
+	return opts, nil
+}
+
+func (opts cliOptions) apply(cfg *Config) error {
+	if opts.set["hidden"] {
+		cfg.ShowHidden = opts.showHidden
+	}
+	if opts.set["filter"] {
+		kind, err := parseFilterKind(opts.filter)
+		if err != nil {
+			return fmt.Errorf("-filter: %w", err)
+		}
+		cfg.Filter = kind
+	}
+	if opts.set["query"] {
+		cfg.Query = opts.query
+	}
+	if opts.set["sort"] {
+		mode, err := parseSortMode(opts.sort)
+		if err != nil {
+			return fmt.Errorf("-sort: %w", err)
+		}
+		cfg.Sort.mode = mode
+	}
+	if opts.set["selection-format"] {
+		f, err := parseOutputFormat(opts.format)
+		if err != nil {
+			return fmt.Errorf("-selection-format: %w", err)
+		}
+		cfg.FileFormat = f
+	}
+	if opts.set["print-selection"] {
+		f, err := parseOutputFormat(opts.stdoutFormat)
+		if err != nil {
+			return fmt.Errorf("-print-selection: %w", err)
+		}
+		cfg.StdoutFormat = f
+	}
+	cfg.CdFile = opts.cdFile
+	cfg.SelectionFile = opts.selectionFile
+	if opts.path == "" {
+		return nil
+	}
+	path, err := filepath.Abs(opts.path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		cfg.Dir = path
+		return nil
+	}
+	cfg.Dir, cfg.File = filepath.Split(path)
+	cfg.Dir = filepath.Clean(cfg.Dir)
+	return nil
+}
+
+func (opts cliOptions) loadConfig() (Config, error) {
+	if opts.configPath == "" {
+		configPath, err := DefaultConfigPath()
+		if err != nil {
+			return DefaultConfig(), err
+		}
+		return LoadConfig(configPath)
+	}
+	f, err := os.Open(opts.configPath)
+	if err != nil {
+		return DefaultConfig(), err
+	}
+	defer f.Close()
+	return parseConfig(f, opts.configPath)
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type Config struct {
+	Keys            KeyMap
+	Styles          Styles
+	PageDist        int
+	HalfDist        int
+	ShowHidden      bool
+	Conflict        ConflictPolicy
+	Sort            sortSettings
+	LongListing     bool
+	Columns         []Column
+	RelativeTime    bool
+	ShowPreview     bool
+	MillerColumns   bool
+	Watch           bool
+	FindDepth       int
+	GrepMaxSize     int
+	Filter          FilterKind
+	Query           string
+	ShellPause      bool
+	Openers         []opener
+	Dir             string
+	File            string
+	CdFile          string
+	SelectionFile   string
+	FileFormat      OutputFormat
+	ClipboardFormat OutputFormat
+	StdoutFormat    OutputFormat
+}
+
+type option func(*Config, string) error
+
+var options = map[string]option{
+	"page_dist": func(c *Config, v string) error {
+		return parsePositiveInt(v, &c.PageDist)
+	},
+	"half_dist": func(c *Config, v string) error {
+		return parsePositiveInt(v, &c.HalfDist)
+	},
+	"show_hidden": func(c *Config, v string) error {
+		return parseBool(v, &c.ShowHidden)
+	},
+	"sort": func(c *Config, v string) (err error) {
+		c.Sort.mode, err = parseSortMode(v)
+		return err
+	},
+	"sort_reverse": func(c *Config, v string) error {
+		return parseBool(v, &c.Sort.reverse)
+	},
+	"dirs_first": func(c *Config, v string) error {
+		return parseBool(v, &c.Sort.dirsFirst)
+	},
+	"long_listing": func(c *Config, v string) error {
+		return parseBool(v, &c.LongListing)
+	},
+	"preview": func(c *Config, v string) error {
+		return parseBool(v, &c.ShowPreview)
+	},
+	"miller_columns": func(c *Config, v string) error {
+		return parseBool(v, &c.MillerColumns)
+	},
+	"find_depth": func(c *Config, v string) error {
+		return parseNonNegativeInt(v, &c.FindDepth)
+	},
+	"shell_pause": func(c *Config, v string) error {
+		return parseBool(v, &c.ShellPause)
+	},
+	"filter": func(c *Config, v string) (err error) {
+		c.Filter, err = parseFilterKind(v)
+		return err
+	},
+	"grep_max_size": func(c *Config, v string) error {
+		return parsePositiveInt(v, &c.GrepMaxSize)
+	},
+	"watch": func(c *Config, v string) error {
+		return parseBool(v, &c.Watch)
+	},
+	"columns": func(c *Config, v string) (err error) {
+		c.Columns, err = parseColumns(v)
+		return err
+	},
+	"time_format": func(c *Config, v string) error {
+		return parseTimeFormat(v, &c.RelativeTime)
+	},
+	"selection_format": func(c *Config, v string) (err error) {
+		c.FileFormat, err = parseOutputFormat(v)
+		return err
+	},
+	"clipboard_format": func(c *Config, v string) (err error) {
+		c.ClipboardFormat, err = parseOutputFormat(v)
+		return err
+	},
+	"stdout_format": func(c *Config, v string) (err error) {
+		c.StdoutFormat, err = parseOutputFormat(v)
+		return err
+	},
+	"conflict": func(c *Config, v string) (err error) {
+		c.Conflict, err = parseConflictPolicy(v)
+		return err
+	},
+}
+
+var colorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|[0-9]{1,3})$`)
+
+func DefaultConfig() Config {
+	return Config{
+		Keys:            DefaultKeyMap(),
+		Styles:          DefaultStyles(),
+		PageDist:        37,
+		HalfDist:        18,
+		ShowHidden:      false,
+		Conflict:        AskPolicy,
+		Sort:            sortSettings{mode: SortName},
+		LongListing:     false,
+		Columns:         []Column{ColPerms, ColOwner, ColSize, ColTime},
+		RelativeTime:    true,
+		Watch:           true,
+		GrepMaxSize:     1 << 20,
+		ShellPause:      true,
+		FileFormat:      OutputFormat{kind: OutputSpace},
+		ClipboardFormat: OutputFormat{kind: OutputSpace},
+	}
+}
+
+func DefaultConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, ConfigSubDir, ConfigFile), nil
+}
+
+func LoadConfig(path string) (Config, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return DefaultConfig(), nil
+	}
+	if err != nil {
+		return DefaultConfig(), err
+	}
+	defer f.Close()
+	return parseConfig(f, path)
+}
+
+func parseConfig(r io.Reader, name string) (Config, error) {
+	cfg := DefaultConfig()
+	var errs []error
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if err := cfg.apply(fields); err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %w", name, lineNo, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+	return cfg, errors.Join(errs...)
+}
+
+func (c *Config) apply(fields []string) error {
+	directive, args := fields[0], fields[1:]
+	switch directive {
+	case "set":
+		if len(args) == 0 || 2 < len(args) {
+			return errors.New(`usage: set <option> [value]`)
+		}
+		opt, ok := options[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown option %q", args[0])
+		}
+		value := "true"
+		if len(args) == 2 {
+			value = args[1]
+		}
+		if err := opt(c, value); err != nil {
+			return fmt.Errorf("option %q: %w", args[0], err)
+		}
+		return nil
+	case "map":
+		if len(args) < 2 {
+			return errors.New(`usage: map <action> <key>...`)
+		}
+		return c.mapKeys(args[0], args[1:])
+	case "unmap":
+		if len(args) != 1 {
+			return errors.New(`usage: unmap <action>`)
+		}
+		return c.mapKeys(args[0], nil)
+	case "open":
+		o, err := parseOpener(args)
+		if err != nil {
+			return err
+		}
+		c.Openers = append(c.Openers, o)
+		return nil
+	case "style":
+		if len(args) < 2 {
+			return errors.New(`usage: style <element> <attribute>...`)
+		}
+		return c.setStyle(args[0], args[1:])
+	}
+	return fmt.Errorf("unknown directive %q", directive)
+}
+
+func (c *Config) mapKeys(action string, keys []string) error {
+	field := reflect.ValueOf(&c.Keys).Elem().FieldByName(action)
+	if !field.IsValid() || field.Type() != reflect.TypeOf(key.Binding{}) {
+		return fmt.Errorf("unknown action %q", action)
+	}
+	old := field.Interface().(key.Binding)
+	if len(keys) == 0 {
+		field.Set(reflect.ValueOf(key.NewBinding(key.WithDisabled())))
+		return nil
+	}
+	for i, k := range keys {
+		if k == "space" {
+			keys[i] = " "
+		}
+	}
+	binding := key.NewBinding(
+		key.WithKeys(keys...),
+		key.WithHelp(strings.Join(keys, "/"), old.Help().Desc),
+	)
+	field.Set(reflect.ValueOf(binding))
+	return nil
+}
+
+func (c *Config) setStyle(element string, attrs []string) error {
+	field := reflect.ValueOf(&c.Styles).Elem().FieldByName(element)
+	if !field.IsValid() || field.Type() != reflect.TypeOf(lipgloss.Style{}) {
+		return fmt.Errorf("unknown style %q", element)
+	}
+	style := field.Interface().(lipgloss.Style)
+	for _, attr := range attrs {
+		name, value, hasValue := strings.Cut(attr, "=")
+		switch name {
+		case "fg", "bg":
+			if !colorPattern.MatchString(value) {
+				return fmt.Errorf("invalid color %q", value)
+			}
+			if name == "fg" {
+				style = style.Foreground(lipgloss.Color(value))
+			} else {
+				style = style.Background(lipgloss.Color(value))
+			}
+		case "bold", "italic", "underline":
+			v := true
+			if hasValue {
+				if err := parseBool(value, &v); err != nil {
+					return fmt.Errorf("attribute %q: %w", name, err)
+				}
+			}
+			switch name {
+			case "bold":
+				style = style.Bold(v)
+			case "italic":
+				style = style.Italic(v)
+			case "underline":
+				style = style.Underline(v)
+			}
+		default:
+			return fmt.Errorf("unknown style attribute %q", name)
+		}
+	}
+	field.Set(reflect.ValueOf(style))
+	return nil
+}
+
+func parsePositiveInt(s string, dest *int) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("expected a positive integer, got %q", s)
+	}
+	*dest = n
+	return nil
+}
+
+func parseNonNegativeInt(s string, dest *int) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return fmt.Errorf("expected a non-negative integer, got %q", s)
+	}
+	*dest = n
+	return nil
+}
+
+func parseBool(s string, dest *bool) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("expected true or false, got %q", s)
+	}
+	*dest = b
+	return nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type ConflictPolicy int
+
+const (
+	AskPolicy ConflictPolicy = iota
+	OverwritePolicy
+	SkipPolicy
+	KeepBothPolicy
+	MergePolicy
+	NewerPolicy
+)
+
+var conflictPolicyNames = map[string]ConflictPolicy{
+	"ask":       AskPolicy,
+	"overwrite": OverwritePolicy,
+	"skip":      SkipPolicy,
+	"keep-both": KeepBothPolicy,
+	"merge":     MergePolicy,
+	"newer":     NewerPolicy,
+}
+
+func parseConflictPolicy(s string) (ConflictPolicy, error) {
+	p, ok := conflictPolicyNames[s]
+	if !ok {
+		return AskPolicy, fmt.Errorf("expected one of ask, overwrite, skip, keep-both, merge or newer, got %q", s)
+	}
+	return p, nil
+}
+
+type conflictReply struct {
+	policy ConflictPolicy
+	all    bool
+}
+
+type jobConflictMsg struct {
+	id       int
+	src      string
+	dest     string
+	mergable bool
+	reply    chan<- conflictReply
+}
+
+func numberedName(base string, i int) string {
+	ext := filepath.Ext(base)
+	if ext == base {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(base, ext)
+	if strings.HasSuffix(stem, ".tar") {
+		stem = strings.TrimSuffix(stem, ".tar")
+		ext = ".tar" + ext
+	}
+	return fmt.Sprintf("%s (%d)%s", stem, i, ext)
+}
+
+func keepBothName(path string) (string, error) {
+	dir, base := filepath.Split(path)
+	for i := 1; ; i++ {
+		candidate := filepath.Join(dir, numberedName(base, i))
+		_, err := os.Lstat(candidate)
+		if os.IsNotExist(err) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+func (c *copier) ask(src, dest string, mergable bool) (ConflictPolicy, error) {
+	reply := make(chan conflictReply, 1)
+	select {
+	case c.msgs <- jobConflictMsg{id: c.id, src: src, dest: dest, mergable: mergable, reply: reply}:
+	case <-c.ctx.Done():
+		return SkipPolicy, c.ctx.Err()
+	}
+	select {
+	case r := <-reply:
+		if r.all {
+			c.policy = r.policy
+		}
+		return r.policy, nil
+	case <-c.ctx.Done():
+		return SkipPolicy, c.ctx.Err()
+	}
+}
+
+func (c *copier) resolve(src, dest string, srcInfo os.FileInfo) (string, bool, error) {
+	destInfo, err := os.Lstat(dest)
+	if os.IsNotExist(err) {
+		return dest, true, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if os.SameFile(srcInfo, destInfo) {
+		if c.kind == MoveJob {
+			return "", false, nil
+		}
+		dest, err = keepBothName(dest)
+		return dest, err == nil, err
+	}
+	mergable := srcInfo.IsDir() && destInfo.IsDir()
+	policy := c.policy
+	if policy == AskPolicy || (policy == MergePolicy && !mergable) {
+		policy, err = c.ask(src, dest, mergable)
+		if err != nil {
+			return "", false, err
+		}
+	}
+	switch policy {
+	case OverwritePolicy:
+		if isWithin(src, dest) {
+			return "", false, fmt.Errorf("cannot overwrite %s with its own contents", dest)
+		}
+		c.overwrote = true
+		return dest, true, os.RemoveAll(dest)
+	case KeepBothPolicy:
+		dest, err = keepBothName(dest)
+		return dest, err == nil, err
+	case MergePolicy:
+		if mergable {
+			return dest, true, nil
+		}
+	case NewerPolicy:
+		if mergable {
+			return dest, true, nil
+		}
+		if srcInfo.ModTime().After(destInfo.ModTime()) && !isWithin(src, dest) {
+			c.overwrote = true
+			return dest, true, os.RemoveAll(dest)
+		}
+	}
+	return "", false, nil
+}
+
+func (c *copier) skip(src string) {
+	c.skipped++
+	c.advance(src)
+}
+
+func (m *Model) handleConflictMsg(msg jobConflictMsg) tea.Cmd {
+	m.conflicts = append(m.conflicts, msg)
+	if m.overlay == JobsOverlay {
+		m.overlay = NoOverlay
+	}
+	j := m.findJob(msg.id)
+	if j == nil {
+		return nil
+	}
+	return waitForJob(j.msgs)
+}
+
+func (m *Model) showQueuedConflict() {
+	if 0 < len(m.conflicts) && m.overlay == NoOverlay {
+		m.overlay = ConflictOverlay
+		m.conflictApplyAll = false
+	}
+}
+
+func (m *Model) answerConflict(policy ConflictPolicy) {
+	c := m.conflicts[0]
+	c.reply <- conflictReply{policy: policy, all: m.conflictApplyAll}
+	m.popConflict()
+}
+
+func (m *Model) popConflict() {
+	m.conflicts = m.conflicts[1:]
+	if len(m.conflicts) == 0 {
+		m.overlay = NoOverlay
+	}
+	m.conflictApplyAll = false
+}
+
+func (m Model) conflictMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if len(m.conflicts) == 0 {
+		m.overlay = NoOverlay
+		return m, nil
+	}
+	mergable := m.conflicts[0].mergable
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.CloseMenu):
+			if j := m.findJob(m.conflicts[0].id); j != nil {
+				j.cancel()
+			}
+			m.popConflict()
+		case key.Matches(msg, m.keys.ConflictApplyAll):
+			m.conflictApplyAll = !m.conflictApplyAll
+		case key.Matches(msg, m.keys.ConflictOverwrite):
+			m.answerConflict(OverwritePolicy)
+		case key.Matches(msg, m.keys.ConflictSkip):
+			m.answerConflict(SkipPolicy)
+		case key.Matches(msg, m.keys.ConflictKeepBoth):
+			m.answerConflict(KeepBothPolicy)
+		case key.Matches(msg, m.keys.ConflictMerge) && mergable:
+			m.answerConflict(MergePolicy)
+		case key.Matches(msg, m.keys.ConflictNewer):
+			m.answerConflict(NewerPolicy)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) conflictView() string {
+	c := m.conflicts[0]
+	s := fmt.Sprintf("%s already exists in %s\n", filepath.Base(c.dest), filepath.Dir(c.dest))
+	s += m.styles.News.Render(fmt.Sprintf("from %s", c.src)) + "\n\n"
+	choices := []key.Binding{
+		m.keys.ConflictOverwrite,
+		m.keys.ConflictSkip,
+		m.keys.ConflictKeepBoth,
+	}
+	if c.mergable {
+		choices = append(choices, m.keys.ConflictMerge)
+	}
+	choices = append(choices, m.keys.ConflictNewer)
+	for _, b := range choices {
+		s += fmt.Sprintf("%s %s\n", m.styles.PathEnd.Render(b.Help().Key), b.Help().Desc)
+	}
+	s += fmt.Sprintf("%s cancel job\n", m.styles.PathEnd.Render(m.keys.CloseMenu.Help().Key))
+	applyAll := "off"
+	if m.conflictApplyAll {
+		applyAll = "on"
+	}
+	h := m.keys.ConflictApplyAll.Help()
+	s += fmt.Sprintf("\n%s %s: %s\n", m.styles.PathEnd.Render(h.Key), h.Desc, applyAll)
+	if 1 < len(m.conflicts) {
+		s += m.styles.News.Render(fmt.Sprintf("%d more conflicts waiting", len(m.conflicts)-1)) + "\n"
+	}
+	return s
+}
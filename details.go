@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Column int
+
+const (
+	ColPerms Column = iota
+	ColOwner
+	ColSize
+	ColTime
+)
+
+var columnNames = []string{"perms", "owner", "size", "time"}
+
+func parseColumns(s string) ([]Column, error) {
+	var cols []Column
+	for _, name := range strings.Split(s, ",") {
+		found := false
+		for i, colName := range columnNames {
+			if name == colName {
+				cols = append(cols, Column(i))
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("expected a comma separated list of %s, got %q", strings.Join(columnNames, ", "), name)
+		}
+	}
+	return cols, nil
+}
+
+func parseTimeFormat(s string, relative *bool) error {
+	switch s {
+	case "relative":
+		*relative = true
+	case "absolute":
+		*relative = false
+	default:
+		return fmt.Errorf("expected relative or absolute, got %q", s)
+	}
+	return nil
+}
+
+func readInfos(entries []os.DirEntry) map[string]os.FileInfo {
+	infos := make(map[string]os.FileInfo, len(entries))
+	for _, f := range entries {
+		if info, err := f.Info(); err == nil {
+			infos[f.Name()] = info
+		}
+	}
+	return infos
+}
+
+func readLinkDirs(dir string, infos map[string]os.FileInfo) map[string]bool {
+	linkDirs := make(map[string]bool)
+	for name, info := range infos {
+		if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		if target, err := os.Stat(filepath.Join(dir, name)); err == nil && target.IsDir() {
+			linkDirs[name] = true
+		}
+	}
+	return linkDirs
+}
+
+func (m Model) fileInfo(f os.DirEntry) (os.FileInfo, error) {
+	if info, ok := m.infos[f.Name()]; ok {
+		return info, nil
+	}
+	return f.Info()
+}
+
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < 0:
+		return t.Format("Jan 02 15:04")
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+	return t.Format("Jan 02 2006")
+}
+
+func (m Model) detailCell(col Column, info os.FileInfo, now time.Time) string {
+	switch col {
+	case ColPerms:
+		return info.Mode().String()
+	case ColOwner:
+		owner, group := fileOwner(info)
+		return owner + " " + group
+	case ColSize:
+		if info.IsDir() {
+			return "-"
+		}
+		return humanSize(info.Size())
+	case ColTime:
+		if m.relativeTime {
+			return relativeTime(info.ModTime(), now)
+		}
+		return info.ModTime().Format("2006-01-02 15:04")
+	}
+	return ""
+}
+
+func (m Model) detailRows(entries []os.DirEntry) []string {
+	now := time.Now()
+	cells := make([][]string, len(entries))
+	widths := make([]int, len(m.columns))
+	for i, f := range entries {
+		info, err := m.fileInfo(f)
+		cells[i] = make([]string, len(m.columns))
+		for j, col := range m.columns {
+			cell := "?"
+			if err == nil {
+				cell = m.detailCell(col, info, now)
+			}
+			cells[i][j] = cell
+			if widths[j] < len(cell) {
+				widths[j] = len(cell)
+			}
+		}
+	}
+	rows := make([]string, len(entries))
+	for i, row := range cells {
+		parts := make([]string, len(row))
+		for j, cell := range row {
+			if m.columns[j] == ColSize {
+				parts[j] = fmt.Sprintf("%*s", widths[j], cell)
+			} else {
+				parts[j] = fmt.Sprintf("%-*s", widths[j], cell)
+			}
+		}
+		rows[i] = m.styles.Details.Render(strings.Join(parts, "  ")) + "  "
+	}
+	return rows
+}
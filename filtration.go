@@ -1,11 +1,18 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/sahilm/fuzzy"
 )
 
@@ -26,11 +33,43 @@ type filteredFiles []filteredFile
 
 type FilterMatchesMsg filteredFiles
 
-type FilterFunc func(string, []string) []Rank
+type filterErrorMsg struct {
+	err error
+}
+
+type FilterFunc func(string, []string) ([]Rank, error)
+
+type FilterKind int
+
+const (
+	FilterFuzzy FilterKind = iota
+	FilterSubstring
+	FilterGlob
+	FilterRegex
+	FilterPrefix
+)
+
+var filterKindNames = []string{"fuzzy", "substring", "glob", "regex", "prefix"}
+
+var filterFuncs = []FilterFunc{DefaultFilter, SubstringFilter, GlobFilter, RegexFilter, PrefixFilter}
+
+func (k FilterKind) String() string {
+	return filterKindNames[k]
+}
+
+func parseFilterKind(s string) (FilterKind, error) {
+	for i, name := range filterKindNames {
+		if name == s {
+			return FilterKind(i), nil
+		}
+	}
+	return FilterFuzzy, fmt.Errorf("expected one of %s, got %q", strings.Join(filterKindNames, ", "), s)
+}
 
 type Rank struct {
 	Index          int
 	MatchedIndexes []int
+	Score          int
 }
 
 func (ff filteredFiles) filteredFilesAsDirEntries() []os.DirEntry {
@@ -41,7 +80,7 @@ func (ff filteredFiles) filteredFilesAsDirEntries() []os.DirEntry {
 	return de
 }
 
-func DefaultFilter(term string, targets []string) []Rank {
+func DefaultFilter(term string, targets []string) ([]Rank, error) {
 	ranks := fuzzy.Find(term, targets)
 	sort.Stable(ranks)
 	result := make([]Rank, len(ranks))
@@ -49,9 +88,130 @@ func DefaultFilter(term string, targets []string) []Rank {
 		result[i] = Rank{
 			Index:          r.Index,
 			MatchedIndexes: r.MatchedIndexes,
+			Score:          r.Score,
+		}
+	}
+	return result, nil
+}
+
+func matchRange(start, end int) []int {
+	indexes := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+func hasUpper(s string) bool {
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func SubstringFilter(term string, targets []string) ([]Rank, error) {
+	caseSensitive := hasUpper(term)
+	fold := func(r rune) rune {
+		if caseSensitive {
+			return r
+		}
+		return unicode.ToLower(r)
+	}
+	needle := []rune(strings.Map(fold, term))
+	var result []Rank
+	for i, t := range targets {
+		var offsets []int
+		var runes []rune
+		for j, r := range t {
+			offsets = append(offsets, j)
+			runes = append(runes, fold(r))
+		}
+		offsets = append(offsets, len(t))
+		for j := 0; j+len(needle) <= len(runes); j++ {
+			if string(runes[j:j+len(needle)]) == string(needle) {
+				result = append(result, Rank{Index: i, MatchedIndexes: matchRange(offsets[j], offsets[j+len(needle)])})
+				break
+			}
 		}
 	}
-	return result
+	return result, nil
+}
+
+func GlobFilter(term string, targets []string) ([]Rank, error) {
+	if _, err := filepath.Match(term, ""); err != nil {
+		return nil, err
+	}
+	var result []Rank
+	for i, t := range targets {
+		if ok, _ := filepath.Match(term, t); ok {
+			result = append(result, Rank{Index: i})
+		}
+	}
+	return result, nil
+}
+
+func RegexFilter(term string, targets []string) ([]Rank, error) {
+	re, err := regexp.Compile(term)
+	if err != nil {
+		return nil, err
+	}
+	var result []Rank
+	for i, t := range targets {
+		if loc := re.FindStringIndex(t); loc != nil {
+			result = append(result, Rank{Index: i, MatchedIndexes: matchRange(loc[0], loc[1])})
+		}
+	}
+	return result, nil
+}
+
+func PrefixFilter(term string, targets []string) ([]Rank, error) {
+	var result []Rank
+	for i, t := range targets {
+		if strings.HasPrefix(t, term) {
+			result = append(result, Rank{Index: i, MatchedIndexes: matchRange(0, len(term))})
+		}
+	}
+	return result, nil
+}
+
+func (m Model) highlight(name string, matches []int, base lipgloss.Style) string {
+	if len(matches) == 0 {
+		return base.Render(name)
+	}
+	matched := make(map[int]bool, len(matches))
+	for _, i := range matches {
+		matched[i] = true
+	}
+	match := m.styles.Match.Copy().Inherit(base)
+	var b strings.Builder
+	var run []byte
+	runMatched := false
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if runMatched {
+			b.WriteString(match.Render(string(run)))
+		} else {
+			b.WriteString(base.Render(string(run)))
+		}
+		run = run[:0]
+	}
+	for i, r := range name {
+		isMatch := false
+		for j := i; j < i+utf8.RuneLen(r); j++ {
+			isMatch = isMatch || matched[j]
+		}
+		if isMatch != runMatched {
+			flush()
+			runMatched = isMatch
+		}
+		run = utf8.AppendRune(run, r)
+	}
+	flush()
+	return b.String()
 }
 
 func filterFiles(m Model) tea.Cmd {
@@ -66,8 +226,12 @@ func filterFiles(m Model) tea.Cmd {
 			targets[i] = f.Name()
 		}
 
+		ranks, err := m.filter(m.filterInput.Value(), targets)
+		if err != nil {
+			return filterErrorMsg{err: err}
+		}
 		filterMatches := filteredFiles{}
-		for _, r := range m.filter(m.filterInput.Value(), targets) {
+		for _, r := range ranks {
 			filterMatches = append(filterMatches, filteredFile{
 				file:    fs[r.Index],
 				matches: r.MatchedIndexes,
@@ -101,8 +265,16 @@ func (m *Model) filterOn() {
 	m.filterInput.Focus()
 }
 
+func (m *Model) setFilterKind(kind FilterKind) {
+	m.filterKind = kind
+	m.filter = filterFuncs[kind]
+	m.filterErr = nil
+	m.filterInput.Prompt = kind.String() + " /"
+}
+
 func (m *Model) filterOff() {
 	m.filterState = Unfiltered
+	m.filterErr = nil
 	m.filterInput.Reset()
 	m.filteredFiles = nil
 	m.news = ""
@@ -117,6 +289,9 @@ func (m Model) filterMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.filterOff()
 		case key.Matches(msg, m.keys.FilterAccept):
 			m.filterAccept()
+		case key.Matches(msg, m.keys.FilterCycle):
+			m.setFilterKind((m.filterKind + 1) % FilterKind(len(filterFuncs)))
+			return m, filterFiles(m)
 		}
 	}
 	newFilterInputModel, inputCmd := m.filterInput.Update(msg)
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	findBatchSize     = 256
+	findFlushInterval = 50 * time.Millisecond
+)
+
+var ignoreFiles = []string{".gitignore", ".navignore"}
+
+type ignoreRule struct {
+	base     string
+	pattern  string
+	dirOnly  bool
+	anchored bool
+}
+
+type findBatchMsg struct {
+	id    int
+	paths []string
+	done  bool
+}
+
+type findMatch struct {
+	path    string
+	score   int
+	matches []int
+}
+
+type finder struct {
+	input   textinput.Model
+	id      int
+	root    string
+	paths   []string
+	matches []findMatch
+	done    bool
+	cancel  context.CancelFunc
+	msgs    chan tea.Msg
+}
+
+func newFindInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "find "
+	return input
+}
+
+// readIgnoreFile supports the common subset of gitignore patterns: globs,
+// trailing / for directories and patterns anchored by a /. Negations (!) are
+// skipped and ** matches like a single *.
+func readIgnoreFile(dir, name string) []ignoreRule {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		rule := ignoreRule{base: dir}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func (r ignoreRule) matches(path string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		ok, _ := filepath.Match(r.pattern, filepath.Base(path))
+		return ok
+	}
+	rel, err := filepath.Rel(r.base, path)
+	if err != nil {
+		return false
+	}
+	ok, _ := filepath.Match(r.pattern, filepath.ToSlash(rel))
+	return ok
+}
+
+func isIgnored(rules []ignoreRule, path string, isDir bool) bool {
+	for _, r := range rules {
+		if r.matches(path, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+type walker struct {
+	ctx        context.Context
+	id         int
+	root       string
+	maxDepth   int
+	showHidden bool
+	msgs       chan<- tea.Msg
+	batch      []string
+	lastSent   time.Time
+}
+
+func (w *walker) send(msg tea.Msg) bool {
+	select {
+	case w.msgs <- msg:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
+
+func (w *walker) add(rel string) bool {
+	w.batch = append(w.batch, rel)
+	if len(w.batch) < findBatchSize && time.Since(w.lastSent) < findFlushInterval {
+		return true
+	}
+	w.lastSent = time.Now()
+	batch := w.batch
+	w.batch = nil
+	return w.send(findBatchMsg{id: w.id, paths: batch})
+}
+
+func (w *walker) walk(dir string, depth int, rules []ignoreRule) bool {
+	if w.ctx.Err() != nil {
+		return false
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return true
+	}
+	for _, name := range ignoreFiles {
+		rules = append(rules[:len(rules):len(rules)], readIgnoreFile(dir, name)...)
+	}
+	for _, f := range entries {
+		path := filepath.Join(dir, f.Name())
+		if f.Name() == ".git" {
+			continue
+		}
+		if !w.showHidden {
+			if hidden, err := isHidden(path); err != nil || hidden {
+				continue
+			}
+		}
+		if isIgnored(rules, path, f.IsDir()) {
+			continue
+		}
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			continue
+		}
+		if !w.add(rel) {
+			return false
+		}
+		if f.IsDir() && (w.maxDepth == 0 || depth < w.maxDepth) {
+			if !w.walk(path, depth+1, rules) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (w *walker) run() {
+	w.lastSent = time.Now()
+	if w.walk(w.root, 1, nil) {
+		w.send(findBatchMsg{id: w.id, paths: w.batch, done: true})
+	}
+}
+
+func (m *Model) openFind() tea.Cmd {
+	m.closeFind()
+	ctx, cancel := context.WithCancel(context.Background())
+	root, err := filepath.Abs(m.currDir)
+	if err != nil {
+		cancel()
+		m.news = fmt.Sprintf("Find error: %s", err)
+		return nil
+	}
+	msgs := make(chan tea.Msg, 1)
+	m.find = finder{
+		input:  newFindInput(),
+		id:     nextID(),
+		root:   root,
+		cancel: cancel,
+		msgs:   msgs,
+	}
+	w := &walker{
+		ctx:        ctx,
+		id:         m.find.id,
+		root:       root,
+		maxDepth:   m.findDepth,
+		showHidden: m.showHidden,
+		msgs:       msgs,
+	}
+	go func() {
+		w.run()
+		close(msgs)
+	}()
+	m.overlay = FindOverlay
+	m.menu.reset()
+	return tea.Batch(m.find.input.Focus(), waitForJob(msgs))
+}
+
+func (m *Model) closeFind() {
+	if m.find.cancel != nil {
+		m.find.cancel()
+	}
+	m.find = finder{}
+	m.overlay = NoOverlay
+}
+
+func (m Model) rankFound(paths []string) []findMatch {
+	term := m.find.input.Value()
+	if term == "" {
+		matches := make([]findMatch, len(paths))
+		for i, p := range paths {
+			matches[i] = findMatch{path: p}
+		}
+		return matches
+	}
+	ranks, _ := DefaultFilter(term, paths)
+	matches := make([]findMatch, len(ranks))
+	for i, r := range ranks {
+		matches[i] = findMatch{path: paths[r.Index], score: r.Score, matches: r.MatchedIndexes}
+	}
+	return matches
+}
+
+func (m *Model) handleFindBatch(msg findBatchMsg) tea.Cmd {
+	if msg.id != m.find.id {
+		return nil
+	}
+	m.find.paths = append(m.find.paths, msg.paths...)
+	m.find.matches = append(m.find.matches, m.rankFound(msg.paths)...)
+	if m.find.input.Value() != "" {
+		sort.SliceStable(m.find.matches, func(i, j int) bool {
+			return m.find.matches[i].score > m.find.matches[j].score
+		})
+	}
+	if msg.done {
+		m.find.done = true
+		return nil
+	}
+	return waitForJob(m.find.msgs)
+}
+
+func (m Model) findMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.keys.FilterOff):
+			m.closeFind()
+			return m, nil
+		case key.Matches(msg, m.keys.JumpPrev):
+			m.menu.up()
+			return m, nil
+		case key.Matches(msg, m.keys.JumpNext):
+			m.menu.down(len(m.find.matches), m.maxHeight-1)
+			return m, nil
+		case key.Matches(msg, m.keys.JumpAccept):
+			if len(m.find.matches) <= m.menu.idx {
+				return m, nil
+			}
+			path := filepath.Join(m.find.root, m.find.matches[m.menu.idx].path)
+			m.closeFind()
+			dir, name := filepath.Split(path)
+			return m, m.changeDir(filepath.Clean(dir), name)
+		}
+	}
+	var cmd tea.Cmd
+	prev := m.find.input.Value()
+	m.find.input, cmd = m.find.input.Update(msg)
+	if m.find.input.Value() != prev {
+		m.find.matches = m.rankFound(m.find.paths)
+		m.menu.reset()
+	}
+	return m, cmd
+}
+
+func (m Model) findView() string {
+	status := fmt.Sprintf("%d/%d", len(m.find.matches), len(m.find.paths))
+	if !m.find.done {
+		status += " searching..."
+	}
+	items := make([]string, len(m.find.matches))
+	for i, f := range m.find.matches {
+		items[i] = f.path
+	}
+	return m.styles.Filter.Render(m.find.input.View()) + "  " + m.styles.News.Render(status) + "\n" +
+		m.menu.view(items, m.maxHeight-1, m.styles)
+}
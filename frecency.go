@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const maxFrecencyEntries = 1000
+
+var frecencyMtx sync.Mutex
+
+type frecencyEntry struct {
+	path string
+	rank float64
+	last int64
+}
+
+type frecencyMsg struct {
+	err error
+}
+
+func (e frecencyEntry) score(now int64) float64 {
+	switch age := now - e.last; {
+	case age < 60*60:
+		return e.rank * 4
+	case age < 24*60*60:
+		return e.rank * 2
+	case age < 7*24*60*60:
+		return e.rank / 2
+	}
+	return e.rank / 4
+}
+
+func loadFrecency() ([]frecencyEntry, error) {
+	path, err := cachePath(FrecencyFile)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return autoImportFrecency(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []frecencyEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		rank, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		last, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, frecencyEntry{path: unquoteField(fields[0]), rank: rank, last: last})
+	}
+	return entries, scanner.Err()
+}
+
+func saveFrecency(entries []frecencyEntry) error {
+	path, err := cachePath(FrecencyFile)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].score(now) > entries[j].score(now)
+	})
+	if maxFrecencyEntries < len(entries) {
+		entries = entries[:maxFrecencyEntries]
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s\t%s\t%d\n", strconv.Quote(e.path), strconv.FormatFloat(e.rank, 'g', -1, 64), e.last)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func mergeFrecency(entries []frecencyEntry, e frecencyEntry) []frecencyEntry {
+	for i := range entries {
+		if entries[i].path == e.path {
+			entries[i].rank += e.rank
+			if entries[i].last < e.last {
+				entries[i].last = e.last
+			}
+			return entries
+		}
+	}
+	return append(entries, e)
+}
+
+// importedFrecency keeps the higher rank, so importing the same database
+// again does not add its weight twice.
+func importedFrecency(entries []frecencyEntry, e frecencyEntry) []frecencyEntry {
+	for i := range entries {
+		if entries[i].path == e.path {
+			entries[i].rank = math.Max(entries[i].rank, e.rank)
+			if entries[i].last < e.last {
+				entries[i].last = e.last
+			}
+			return entries
+		}
+	}
+	return append(entries, e)
+}
+
+func recordFrecency(dir string) tea.Cmd {
+	return func() tea.Msg {
+		frecencyMtx.Lock()
+		defer frecencyMtx.Unlock()
+		entries, err := loadFrecency()
+		if err != nil {
+			return frecencyMsg{err: err}
+		}
+		entries = mergeFrecency(entries, frecencyEntry{path: dir, rank: 1, last: time.Now().Unix()})
+		return frecencyMsg{err: saveFrecency(entries)}
+	}
+}
+
+func importFrecency(path string) (int, error) {
+	frecencyMtx.Lock()
+	defer frecencyMtx.Unlock()
+	imported, err := readForeignFrecency(path)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := loadFrecency()
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range imported {
+		entries = importedFrecency(entries, e)
+	}
+	return len(imported), saveFrecency(entries)
+}
+
+func autoImportFrecency() []frecencyEntry {
+	for _, path := range foreignFrecencyPaths() {
+		if entries, err := readForeignFrecency(path); err == nil {
+			return entries
+		}
+	}
+	return nil
+}
+
+func foreignFrecencyPaths() []string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		dataDir = filepath.Join(homeDir, ".local", "share")
+	}
+	zoxideDir := os.Getenv("_ZO_DATA_DIR")
+	if zoxideDir == "" {
+		zoxideDir = filepath.Join(dataDir, "zoxide")
+	}
+	z := os.Getenv("_Z_DATA")
+	if z == "" {
+		z = filepath.Join(homeDir, ".z")
+	}
+	return []string{
+		filepath.Join(zoxideDir, "db.zo"),
+		z,
+		filepath.Join(dataDir, "autojump", "autojump.txt"),
+	}
+}
+
+func readForeignFrecency(path string) ([]frecencyEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case filepath.Ext(path) == ".zo":
+		return parseZoxide(data)
+	case filepath.Base(path) == "autojump.txt":
+		return parseAutojump(data, time.Now().Unix())
+	}
+	return parseZ(data)
+}
+
+func parseZoxide(data []byte) ([]frecencyEntry, error) {
+	r := bytes.NewReader(data)
+	var version uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != 3 {
+		return nil, fmt.Errorf("unsupported zoxide database version %d", version)
+	}
+	var n uint64
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return nil, err
+	}
+	var entries []frecencyEntry
+	for i := uint64(0); i < n; i++ {
+		var pathLen uint64
+		if err := binary.Read(r, binary.LittleEndian, &pathLen); err != nil {
+			return nil, err
+		}
+		if uint64(r.Len()) < pathLen {
+			return nil, io.ErrUnexpectedEOF
+		}
+		path := make([]byte, pathLen)
+		if _, err := io.ReadFull(r, path); err != nil {
+			return nil, err
+		}
+		var rank float64
+		var last uint64
+		if err := binary.Read(r, binary.LittleEndian, &rank); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &last); err != nil {
+			return nil, err
+		}
+		entries = append(entries, frecencyEntry{path: string(path), rank: rank, last: int64(last)})
+	}
+	return entries, nil
+}
+
+func parseZ(data []byte) ([]frecencyEntry, error) {
+	var entries []frecencyEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, "|")
+		if len(fields) != 3 {
+			continue
+		}
+		rank, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		last, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, frecencyEntry{path: fields[0], rank: rank, last: last})
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no z entries found")
+	}
+	return entries, nil
+}
+
+func parseAutojump(data []byte, now int64) ([]frecencyEntry, error) {
+	var entries []frecencyEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		weight, path, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		rank, err := strconv.ParseFloat(weight, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, frecencyEntry{path: path, rank: rank, last: now})
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no autojump entries found")
+	}
+	return entries, nil
+}
+
+func rankFrecency(term string, entries []frecencyEntry) []frecencyEntry {
+	now := time.Now().Unix()
+	term = strings.ReplaceAll(term, " ", "")
+	if term == "" {
+		ranked := append([]frecencyEntry(nil), entries...)
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return ranked[i].score(now) > ranked[j].score(now)
+		})
+		return ranked
+	}
+	targets := make([]string, len(entries))
+	for i, e := range entries {
+		targets[i] = e.path
+	}
+	ranks, _ := DefaultFilter(term, targets)
+	minScore := math.MaxInt
+	for _, r := range ranks {
+		minScore = min(minScore, r.Score)
+	}
+	type match struct {
+		entry  frecencyEntry
+		weight float64
+	}
+	matches := make([]match, len(ranks))
+	for i, r := range ranks {
+		e := entries[r.Index]
+		matches[i] = match{entry: e, weight: e.score(now) * float64(r.Score-minScore+1)}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].weight > matches[j].weight
+	})
+	ranked := make([]frecencyEntry, len(matches))
+	for i, mt := range matches {
+		ranked[i] = mt.entry
+	}
+	return ranked
+}
+
+func newJumpInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "z "
+	return input
+}
+
+func (m *Model) openJump() tea.Cmd {
+	frecencyMtx.Lock()
+	entries, err := loadFrecency()
+	frecencyMtx.Unlock()
+	if err != nil {
+		m.news = fmt.Sprintf("Frecency error: %s", err)
+		return nil
+	}
+	m.jumpEntries = entries
+	m.jumpInput.Reset()
+	m.jumpMatches = rankFrecency("", entries)
+	m.overlay = JumpOverlay
+	m.menu.reset()
+	return m.jumpInput.Focus()
+}
+
+func (m *Model) importForeignFrecency() {
+	total, found := 0, false
+	for _, path := range foreignFrecencyPaths() {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		found = true
+		n, err := importFrecency(path)
+		if err != nil {
+			m.news = fmt.Sprintf("Import error: %s: %s", path, err)
+			return
+		}
+		total += n
+	}
+	if !found {
+		m.news = "No zoxide, z or autojump database found"
+		return
+	}
+	m.news = fmt.Sprintf("Imported %d directories", total)
+	frecencyMtx.Lock()
+	entries, err := loadFrecency()
+	frecencyMtx.Unlock()
+	if err != nil {
+		m.news = fmt.Sprintf("Frecency error: %s", err)
+		return
+	}
+	m.jumpEntries = entries
+	m.jumpMatches = rankFrecency(m.jumpInput.Value(), entries)
+	m.menu.reset()
+}
+
+func (m Model) jumpMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.keys.FilterOff):
+			m.overlay = NoOverlay
+			m.jumpInput.Blur()
+			return m, nil
+		case key.Matches(msg, m.keys.JumpPrev):
+			m.menu.up()
+			return m, nil
+		case key.Matches(msg, m.keys.JumpNext):
+			m.menu.down(len(m.jumpMatches), m.maxHeight-1)
+			return m, nil
+		case key.Matches(msg, m.keys.JumpImport):
+			m.importForeignFrecency()
+			return m, nil
+		case key.Matches(msg, m.keys.JumpAccept):
+			m.overlay = NoOverlay
+			m.jumpInput.Blur()
+			if len(m.jumpMatches) <= m.menu.idx {
+				return m, nil
+			}
+			dir := m.jumpMatches[m.menu.idx].path
+			if !isDirAccessible(dir) {
+				m.news = "Directory no longer exists: " + dir
+				return m, nil
+			}
+			return m, m.changeDir(dir, "")
+		}
+	}
+	var cmd tea.Cmd
+	prev := m.jumpInput.Value()
+	m.jumpInput, cmd = m.jumpInput.Update(msg)
+	if m.jumpInput.Value() != prev {
+		m.jumpMatches = rankFrecency(m.jumpInput.Value(), m.jumpEntries)
+		m.menu.reset()
+	}
+	return m, cmd
+}
+
+func (m Model) jumpView() string {
+	items := make([]string, len(m.jumpMatches))
+	for i, e := range m.jumpMatches {
+		items[i] = e.path
+	}
+	return m.styles.Filter.Render(m.jumpInput.View()) + "\n" + m.menu.view(items, m.maxHeight-1, m.styles)
+}
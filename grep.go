@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	mapset "github.com/deckarep/golang-set"
+)
+
+const (
+	maxGrepResults  = 10000
+	maxSnippetWidth = 200
+)
+
+type grepResult struct {
+	path string
+	line int
+	text string
+}
+
+type grepBatchMsg struct {
+	id      int
+	results []grepResult
+	scanned int
+	done    bool
+}
+
+type editorMsg struct {
+	err error
+}
+
+type grepper struct {
+	input    textinput.Model
+	regex    bool
+	id       int
+	root     string
+	results  []grepResult
+	scanned  int
+	started  bool
+	done     bool
+	cancel   context.CancelFunc
+	msgs     chan tea.Msg
+	matchErr error
+}
+
+func newGrepInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "grep "
+	return input
+}
+
+func grepMatcher(pattern string, regex bool) (func(string) bool, error) {
+	if !regex {
+		return func(line string) bool {
+			return strings.Contains(line, pattern)
+		}, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+func grepFile(ctx context.Context, path string, maxSize int, match func(string) bool, emit func(grepResult) bool) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if int64(maxSize) < info.Size() {
+		return true
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	head := make([]byte, 8000)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return true
+	}
+	if isBinary(head[:n]) {
+		return true
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return true
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxSize+1)
+	for line := 1; scanner.Scan(); line++ {
+		if ctx.Err() != nil {
+			return true
+		}
+		text := scanner.Text()
+		if !match(text) {
+			continue
+		}
+		text = sanitizeLine(strings.TrimSpace(text))
+		if r := []rune(text); maxSnippetWidth < len(r) {
+			text = string(r[:maxSnippetWidth])
+		}
+		if !emit(grepResult{path: path, line: line, text: text}) {
+			return true
+		}
+	}
+	return true
+}
+
+func runGrep(ctx context.Context, cancel context.CancelFunc, id int, root string, showHidden bool, maxSize int, match func(string) bool, msgs chan<- tea.Msg) {
+	defer close(msgs)
+	walkMsgs := make(chan tea.Msg, 1)
+	w := &walker{ctx: ctx, root: root, showHidden: showHidden, msgs: walkMsgs}
+	go func() {
+		w.run()
+		close(walkMsgs)
+	}()
+
+	paths := make(chan string, 64)
+	go func() {
+		defer close(paths)
+		for msg := range walkMsgs {
+			for _, rel := range msg.(findBatchMsg).paths {
+				select {
+				case paths <- filepath.Join(root, rel):
+				case <-ctx.Done():
+					return
+				}
+			}
+			if msg.(findBatchMsg).done {
+				return
+			}
+		}
+	}()
+
+	results := make(chan grepResult, 64)
+	var scanned sync.WaitGroup
+	var mu sync.Mutex
+	count := 0
+	for i := 0; i < runtime.NumCPU(); i++ {
+		scanned.Add(1)
+		go func() {
+			defer scanned.Done()
+			for path := range paths {
+				regular := grepFile(ctx, path, maxSize, match, func(r grepResult) bool {
+					select {
+					case results <- r:
+						return true
+					case <-ctx.Done():
+						return false
+					}
+				})
+				if regular {
+					mu.Lock()
+					count++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	go func() {
+		scanned.Wait()
+		close(results)
+	}()
+
+	var batch []grepResult
+	total := 0
+	ticker := time.NewTicker(findFlushInterval)
+	defer ticker.Stop()
+	send := func(done bool) bool {
+		mu.Lock()
+		msg := grepBatchMsg{id: id, results: batch, scanned: count, done: done}
+		mu.Unlock()
+		batch = nil
+		select {
+		case msgs <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for {
+		select {
+		case r, ok := <-results:
+			if !ok {
+				send(true)
+				return
+			}
+			rel, err := filepath.Rel(root, r.path)
+			if err == nil {
+				r.path = rel
+			}
+			batch = append(batch, r)
+			total++
+			if maxGrepResults <= total {
+				send(true)
+				cancel()
+				return
+			}
+		case <-ticker.C:
+			if 0 < len(batch) && !send(false) {
+				return
+			}
+		}
+	}
+}
+
+func (m *Model) openGrep() {
+	m.closeGrep()
+	m.grep = grepper{input: newGrepInput(), regex: m.grep.regex}
+	m.overlay = GrepOverlay
+	m.menu.reset()
+	m.grep.input.Focus()
+}
+
+func (m *Model) closeGrep() {
+	if m.grep.cancel != nil {
+		m.grep.cancel()
+	}
+	m.grep.cancel = nil
+	m.overlay = NoOverlay
+}
+
+func (m *Model) startGrep() tea.Cmd {
+	pattern := m.grep.input.Value()
+	if pattern == "" {
+		return nil
+	}
+	match, err := grepMatcher(pattern, m.grep.regex)
+	if err != nil {
+		m.grep.matchErr = err
+		return nil
+	}
+	root, err := filepath.Abs(m.currDir)
+	if err != nil {
+		m.grep.matchErr = err
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs := make(chan tea.Msg, 1)
+	m.grep.id = nextID()
+	m.grep.root = root
+	m.grep.results = nil
+	m.grep.scanned = 0
+	m.grep.started = true
+	m.grep.done = false
+	m.grep.matchErr = nil
+	m.grep.cancel = cancel
+	m.grep.msgs = msgs
+	m.grep.input.Blur()
+	m.menu.reset()
+	go runGrep(ctx, cancel, m.grep.id, root, m.showHidden, m.grepMaxSize, match, msgs)
+	return waitForJob(msgs)
+}
+
+func (m *Model) handleGrepBatch(msg grepBatchMsg) tea.Cmd {
+	if msg.id != m.grep.id {
+		return nil
+	}
+	m.grep.results = append(m.grep.results, msg.results...)
+	m.grep.scanned = msg.scanned
+	if msg.done {
+		m.grep.done = true
+		m.grep.cancel = nil
+		return nil
+	}
+	return waitForJob(m.grep.msgs)
+}
+
+func (m *Model) toggleSelectPath(path string) {
+	dir, name := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	fileSet, ok := m.selection[dir]
+	if !ok {
+		fileSet = mapset.NewSet()
+		m.selection[dir] = fileSet
+	}
+	if !fileSet.Contains(name) {
+		fileSet.Add(name)
+		return
+	}
+	fileSet.Remove(name)
+	if fileSet.Cardinality() == 0 {
+		delete(m.selection, dir)
+	}
+}
+
+func (m Model) grepMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if ok && key.Matches(keyMsg, m.keys.FilterOff) {
+		m.closeGrep()
+		return m, nil
+	}
+	if m.grep.input.Focused() {
+		switch {
+		case ok && key.Matches(keyMsg, m.keys.JumpAccept):
+			return m, m.startGrep()
+		case ok && key.Matches(keyMsg, m.keys.GrepRegex):
+			m.grep.regex = !m.grep.regex
+			m.grep.matchErr = nil
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.grep.input, cmd = m.grep.input.Update(msg)
+		return m, cmd
+	}
+	if !ok {
+		return m, nil
+	}
+	hasResult := m.menu.idx < len(m.grep.results)
+	switch {
+	case key.Matches(keyMsg, m.keys.Up):
+		m.menu.up()
+	case key.Matches(keyMsg, m.keys.Down):
+		m.menu.down(len(m.grep.results), m.maxHeight-1)
+	case key.Matches(keyMsg, m.keys.Grep):
+		if m.grep.cancel != nil {
+			m.grep.cancel()
+			m.grep.cancel = nil
+			m.grep.done = true
+		}
+		m.grep.input.Focus()
+	case key.Matches(keyMsg, m.keys.JumpAccept) && hasResult:
+		path := filepath.Join(m.grep.root, m.grep.results[m.menu.idx].path)
+		m.closeGrep()
+		dir, name := filepath.Split(path)
+		return m, m.changeDir(filepath.Clean(dir), name)
+	case key.Matches(keyMsg, m.keys.GrepEdit) && hasResult:
+		r := m.grep.results[m.menu.idx]
+		cmd := editorCommand(fmt.Sprintf("+%d", r.line), filepath.Join(m.grep.root, r.path))
+		return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return editorMsg{err: err}
+		})
+	case key.Matches(keyMsg, m.keys.ToggleSelect) && hasResult:
+		m.toggleSelectPath(filepath.Join(m.grep.root, m.grep.results[m.menu.idx].path))
+		m.menu.down(len(m.grep.results), m.maxHeight-1)
+	}
+	return m, nil
+}
+
+func (m Model) grepView() string {
+	mode := "literal"
+	if m.grep.regex {
+		mode = "regex"
+	}
+	status := mode
+	switch {
+	case m.grep.matchErr != nil:
+		status = m.grep.matchErr.Error()
+	case m.grep.started:
+		status = fmt.Sprintf("%s, %d matches in %d files scanned", mode, len(m.grep.results), m.grep.scanned)
+		if !m.grep.done {
+			status += ", searching..."
+		}
+	}
+	header := m.styles.Filter.Render(m.grep.input.View()) + "  " + m.styles.News.Render(status) + "\n"
+	items := make([]string, len(m.grep.results))
+	for i, r := range m.grep.results {
+		items[i] = fmt.Sprintf("%s:%d: %s", r.path, r.line, r.text)
+		dir, name := filepath.Split(filepath.Join(m.grep.root, r.path))
+		if fileSet, ok := m.selection[filepath.Clean(dir)]; ok && fileSet.Contains(name) {
+			items[i] = m.styles.Selected.Render(items[i])
+		}
+	}
+	return header + m.menu.view(items, m.maxHeight-1, m.styles)
+}
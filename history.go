@@ -0,0 +1,99 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) pushHistory(dir string) {
+	if 0 < len(m.history) && m.history[m.histPos] == dir {
+		return
+	}
+	m.history = append(m.history[:m.histPos+1], dir)
+	m.histPos = len(m.history) - 1
+}
+
+func (m *Model) historyJump(pos int) tea.Cmd {
+	dir := m.history[pos]
+	if !isDirAccessible(dir) {
+		m.history = append(m.history[:pos], m.history[pos+1:]...)
+		if pos <= m.histPos {
+			m.histPos--
+		}
+		m.news = "Directory no longer exists: " + dir
+		return nil
+	}
+	m.histPos = pos
+	return m.visitDir(dir, "")
+}
+
+func (m *Model) historyBack() tea.Cmd {
+	if m.histPos == 0 {
+		m.news = "Already at the oldest directory"
+		return nil
+	}
+	return m.historyJump(m.histPos - 1)
+}
+
+func (m *Model) historyForward() tea.Cmd {
+	if len(m.history)-1 <= m.histPos {
+		m.news = "Already at the newest directory"
+		return nil
+	}
+	return m.historyJump(m.histPos + 1)
+}
+
+func (m *Model) toggleDir() tea.Cmd {
+	if m.prevDir == "" {
+		m.news = "No previous directory"
+		return nil
+	}
+	if !isDirAccessible(m.prevDir) {
+		m.news = "Directory no longer exists: " + m.prevDir
+		return nil
+	}
+	return m.changeDir(m.prevDir, "")
+}
+
+func (m *Model) recordVisit(dir string) {
+	visited := []string{dir}
+	for _, d := range m.visited {
+		if d != dir {
+			visited = append(visited, d)
+		}
+	}
+	m.visited = visited
+}
+
+func (m *Model) openHistory() {
+	m.overlay = HistoryOverlay
+	m.menu.reset()
+}
+
+func (m Model) historyMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		dirs := m.visited
+		switch {
+		case key.Matches(msg, m.keys.CloseMenu, m.keys.ShowHistory):
+			m.overlay = NoOverlay
+		case key.Matches(msg, m.keys.Up):
+			m.menu.up()
+		case key.Matches(msg, m.keys.Down):
+			m.menu.down(len(dirs), m.maxHeight)
+		case key.Matches(msg, m.keys.Confirm, m.keys.Right) && m.menu.idx < len(dirs):
+			m.overlay = NoOverlay
+			dir := dirs[m.menu.idx]
+			if !isDirAccessible(dir) {
+				m.news = "Directory no longer exists: " + dir
+				return m, nil
+			}
+			return m, m.changeDir(dir, "")
+		}
+	}
+	return m, nil
+}
+
+func (m Model) historyView() string {
+	return m.menu.view(m.visited, m.maxHeight, m.styles)
+}
@@ -0,0 +1,668 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const progressInterval = 100 * time.Millisecond
+
+type JobKind int
+
+const (
+	CopyJob JobKind = iota
+	MoveJob
+	DeleteJob
+	TrashJob
+	RestoreJob
+	PurgeJob
+)
+
+func (k JobKind) String() string {
+	switch k {
+	case MoveJob:
+		return "move"
+	case DeleteJob:
+		return "delete"
+	case TrashJob:
+		return "trash"
+	case RestoreJob:
+		return "restore"
+	case PurgeJob:
+		return "purge"
+	default:
+		return "copy"
+	}
+}
+
+type JobState int
+
+const (
+	JobRunning JobState = iota
+	JobDone
+	JobFailed
+	JobCancelled
+)
+
+type transfer struct {
+	src  string
+	dest string
+}
+
+type jobProgress struct {
+	bytesDone  int64
+	bytesTotal int64
+	filesDone  int
+	filesTotal int
+}
+
+type job struct {
+	id        int
+	kind      JobKind
+	policy    ConflictPolicy
+	journal   journalAction
+	entryID   int64
+	transfers []transfer
+	state     JobState
+	progress  jobProgress
+	started   time.Time
+	finished  time.Time
+	err       error
+	cancel    context.CancelFunc
+	msgs      chan tea.Msg
+	deselect  bool
+	uncut     bool
+}
+
+type jobProgressMsg struct {
+	id       int
+	progress jobProgress
+}
+
+type jobDoneMsg struct {
+	id        int
+	progress  jobProgress
+	done      []journalItem
+	overwrote bool
+	err       error
+	cancelled bool
+	succeeded []string
+	skipped   []string
+}
+
+type copier struct {
+	ctx       context.Context
+	id        int
+	kind      JobKind
+	policy    ConflictPolicy
+	msgs      chan<- tea.Msg
+	progress  jobProgress
+	skipped   int
+	overwrote bool
+	done      []transfer
+	lastSent  time.Time
+}
+
+func waitForJob(msgs <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-msgs
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+func (m *Model) startJob(kind JobKind, transfers []transfer, action journalAction) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := job{
+		id:        nextID(),
+		kind:      kind,
+		policy:    m.conflictPolicy,
+		journal:   action,
+		transfers: transfers,
+		state:     JobRunning,
+		started:   time.Now(),
+		cancel:    cancel,
+		msgs:      make(chan tea.Msg, 1),
+	}
+	m.jobs = append(m.jobs, j)
+	go j.run(ctx)
+	return waitForJob(j.msgs)
+}
+
+func (m *Model) findJob(id int) *job {
+	for i := range m.jobs {
+		if m.jobs[i].id == id {
+			return &m.jobs[i]
+		}
+	}
+	return nil
+}
+
+func (m *Model) handleJobMsg(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case jobProgressMsg:
+		j := m.findJob(msg.id)
+		if j == nil {
+			return nil
+		}
+		j.progress = msg.progress
+		return waitForJob(j.msgs)
+	case jobDoneMsg:
+		j := m.findJob(msg.id)
+		if j == nil {
+			return nil
+		}
+		j.finished = time.Now()
+		j.progress = msg.progress
+		j.err = msg.err
+		j.cancel()
+		m.dropConflicts(j.id)
+		if j.deselect {
+			for _, p := range msg.succeeded {
+				m.deselect(p)
+			}
+		}
+		if j.uncut {
+			m.uncut(msg.succeeded)
+		}
+		switch {
+		case msg.cancelled:
+			j.state = JobCancelled
+			m.news = fmt.Sprintf("Job #%d cancelled", j.id)
+		case msg.err != nil:
+			j.state = JobFailed
+			m.news = fmt.Sprintf("Job #%d failed: %s", j.id, msg.err)
+		default:
+			j.state = JobDone
+			m.news = fmt.Sprintf("Job #%d finished: %s", j.id, j.summary())
+			if 0 < len(msg.skipped) {
+				m.news += fmt.Sprintf(", skipped %d", len(msg.skipped))
+			}
+		}
+		if err := j.updateJournal(msg); err != nil {
+			m.news = fmt.Sprintf("Journal error: %s", err)
+		}
+		if m.overlay == TrashOverlay {
+			m.reloadTrash()
+		}
+		return tea.Batch(m.readDir(m.currDir), m.reloadPane())
+	}
+	return nil
+}
+
+func (m *Model) dropConflicts(id int) {
+	var conflicts []jobConflictMsg
+	for _, c := range m.conflicts {
+		if c.id != id {
+			conflicts = append(conflicts, c)
+		}
+	}
+	m.conflicts = conflicts
+	if len(m.conflicts) == 0 && m.overlay == ConflictOverlay {
+		m.overlay = NoOverlay
+	}
+}
+
+func (j job) run(ctx context.Context) {
+	c := &copier{ctx: ctx, id: j.id, kind: j.kind, policy: j.policy, msgs: j.msgs}
+	for _, t := range j.transfers {
+		c.measure(t.src)
+	}
+	c.report(true)
+
+	var errs []error
+	var succeeded, skipped []string
+	for _, t := range j.transfers {
+		if ctx.Err() != nil {
+			break
+		}
+		if t.dest != t.src && isWithin(t.dest, t.src) {
+			errs = append(errs, fmt.Errorf("cannot %s %s into itself", j.kind, t.src))
+			continue
+		}
+		skips := c.skipped
+		var err error
+		switch j.kind {
+		case MoveJob:
+			if j.journal == journalUndo {
+				os.MkdirAll(filepath.Dir(t.dest), os.ModePerm)
+			}
+			err = c.move(t.src, t.dest)
+		case DeleteJob:
+			c.advance(t.src)
+			err = os.RemoveAll(t.src)
+		case TrashJob:
+			err = c.trash(t.src)
+		case RestoreJob:
+			err = c.restore(t.src, t.dest)
+		case PurgeJob:
+			c.advance(t.src)
+			err = purgeTrashed(t.src)
+		default:
+			err = c.copy(t.src, t.dest)
+		}
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case skips < c.skipped:
+			skipped = append(skipped, t.src)
+		case ctx.Err() == nil:
+			succeeded = append(succeeded, t.src)
+		}
+	}
+	done := make([]journalItem, len(c.done))
+	for i, t := range c.done {
+		done[i] = journalItem{Src: t.src, Dest: t.dest, Fingerprint: takeFingerprint(t.dest)}
+	}
+	j.msgs <- jobDoneMsg{
+		id:        j.id,
+		progress:  c.progress,
+		done:      done,
+		overwrote: c.overwrote,
+		err:       errors.Join(errs...),
+		cancelled: ctx.Err() != nil,
+		succeeded: succeeded,
+		skipped:   skipped,
+	}
+}
+
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (c *copier) measure(path string) {
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		c.progress.filesTotal++
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			c.progress.bytesTotal += info.Size()
+		}
+		return nil
+	})
+}
+
+func (c *copier) report(force bool) {
+	if !force && time.Since(c.lastSent) < progressInterval {
+		return
+	}
+	c.lastSent = time.Now()
+	select {
+	case c.msgs <- jobProgressMsg{id: c.id, progress: c.progress}:
+	default:
+	}
+}
+
+func (c *copier) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	c.progress.bytesDone += int64(len(p))
+	c.report(false)
+	return len(p), nil
+}
+
+func (c *copier) copy(src, dest string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	dest, ok, err := c.resolve(src, dest, info)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		c.skip(src)
+		return nil
+	}
+	if _, err := os.Lstat(dest); err == nil {
+		return c.copyInto(src, dest)
+	}
+	err = c.copyTree(src, dest, info)
+	c.created(src, dest)
+	return err
+}
+
+func (c *copier) copyTree(src, dest string, info os.FileInfo) error {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return c.copySymlink(src, dest)
+	case info.IsDir():
+		return c.copyDir(src, dest)
+	default:
+		return c.copyFile(src, dest)
+	}
+}
+
+func (c *copier) copyInto(src, dest string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, f := range entries {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+		err := c.copy(filepath.Join(src, f.Name()), filepath.Join(dest, f.Name()))
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *copier) created(src, dest string) {
+	if _, err := os.Lstat(dest); err == nil {
+		c.done = append(c.done, transfer{src: src, dest: dest})
+	}
+}
+
+func (c *copier) move(src, dest string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	dest, ok, err := c.resolve(src, dest, info)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		c.skip(src)
+		return nil
+	}
+
+	if _, err := os.Lstat(dest); err == nil {
+		return c.mergeDir(src, dest)
+	}
+
+	same, err := sameDevice(src, filepath.Dir(dest))
+	if err != nil {
+		return err
+	}
+	if same {
+		err = os.Rename(src, dest)
+		if err == nil {
+			c.advance(dest)
+			c.created(src, dest)
+			return nil
+		}
+		if !errors.Is(err, syscall.EXDEV) {
+			return err
+		}
+	}
+
+	if err := c.copyTree(src, dest, info); err != nil {
+		os.RemoveAll(dest)
+		return err
+	}
+	if err := verifyCopy(src, dest); err != nil {
+		os.RemoveAll(dest)
+		return fmt.Errorf("keeping %s: %w", src, err)
+	}
+	if err := os.RemoveAll(src); err != nil {
+		return err
+	}
+	c.created(src, dest)
+	return nil
+}
+
+func (c *copier) mergeDir(src, dest string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, f := range entries {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+		err := c.move(filepath.Join(src, f.Name()), filepath.Join(dest, f.Name()))
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		os.Remove(src)
+	}
+	return errors.Join(errs...)
+}
+
+func (c *copier) advance(path string) {
+	moved := &copier{ctx: c.ctx}
+	moved.measure(path)
+	c.progress.filesDone += moved.progress.filesTotal
+	c.progress.bytesDone += moved.progress.bytesTotal
+	c.report(false)
+}
+
+func verifyCopy(src, dest string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		srcInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		destInfo, err := os.Lstat(filepath.Join(dest, rel))
+		if err != nil {
+			return fmt.Errorf("copy incomplete: %w", err)
+		}
+		if srcInfo.Mode().Type() != destInfo.Mode().Type() {
+			return fmt.Errorf("copy incomplete: %s has a different type", filepath.Join(dest, rel))
+		}
+		if srcInfo.Mode().IsRegular() && srcInfo.Size() != destInfo.Size() {
+			return fmt.Errorf("copy incomplete: %s has a different size", filepath.Join(dest, rel))
+		}
+		if srcInfo.Mode()&os.ModeSymlink != 0 {
+			srcTarget, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			destTarget, err := os.Readlink(filepath.Join(dest, rel))
+			if err != nil || srcTarget != destTarget {
+				return fmt.Errorf("copy incomplete: %s points elsewhere", filepath.Join(dest, rel))
+			}
+		}
+		return nil
+	})
+}
+
+func (c *copier) copyFile(src, dest string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	destFile, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+
+	_, err = io.Copy(io.MultiWriter(destFile, c), srcFile)
+	if err != nil {
+		destFile.Close()
+		os.Remove(dest)
+		return err
+	}
+	c.progress.filesDone++
+
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(dest, srcInfo.Mode())
+}
+
+func (c *copier) copyDir(src, dest string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dest, srcInfo.Mode())
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, f := range entries {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+		info, err := f.Info()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		err = c.copyTree(filepath.Join(src, f.Name()), filepath.Join(dest, f.Name()), info)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *copier) copySymlink(src, dest string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if err := os.Symlink(target, dest); err != nil {
+		return err
+	}
+	c.progress.filesDone++
+	return nil
+}
+
+func (j job) eta() time.Duration {
+	p := j.progress
+	elapsed := time.Since(j.started)
+	if p.bytesDone == 0 || p.bytesTotal <= p.bytesDone {
+		return 0
+	}
+	rate := float64(p.bytesDone) / elapsed.Seconds()
+	return time.Duration(float64(p.bytesTotal-p.bytesDone) / rate * float64(time.Second))
+}
+
+func (j job) summary() string {
+	p := j.progress
+	return fmt.Sprintf("%d/%d files, %s/%s",
+		p.filesDone, p.filesTotal, humanSize(p.bytesDone), humanSize(p.bytesTotal))
+}
+
+func (j job) String() string {
+	s := fmt.Sprintf("#%d %s %s", j.id, j.kind, j.summary())
+	switch j.state {
+	case JobRunning:
+		s += fmt.Sprintf(", ETA %s", j.eta().Round(time.Second))
+	case JobDone:
+		s += fmt.Sprintf(", done in %s", j.finished.Sub(j.started).Round(time.Second))
+	case JobFailed:
+		s += fmt.Sprintf(", failed: %s", j.err)
+	case JobCancelled:
+		s += ", cancelled"
+	}
+	return s
+}
+
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for x := n / unit; unit <= x; x /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func (m Model) runningJobs() []job {
+	var running []job
+	for _, j := range m.jobs {
+		if j.state == JobRunning {
+			running = append(running, j)
+		}
+	}
+	return running
+}
+
+func (m Model) jobStatus() string {
+	running := m.runningJobs()
+	switch len(running) {
+	case 0:
+		return ""
+	case 1:
+		return running[0].String()
+	}
+	var done, total int64
+	for _, j := range running {
+		done += j.progress.bytesDone
+		total += j.progress.bytesTotal
+	}
+	return fmt.Sprintf("%d jobs running, %s/%s", len(running), humanSize(done), humanSize(total))
+}
+
+func (m Model) jobsMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.CloseMenu, m.keys.ShowJobs):
+			m.overlay = NoOverlay
+		case key.Matches(msg, m.keys.Up):
+			m.menu.up()
+		case key.Matches(msg, m.keys.Down):
+			m.menu.down(len(m.jobs), m.maxHeight)
+		case key.Matches(msg, m.keys.CancelJob) && m.menu.idx < len(m.jobs):
+			j := m.jobs[m.menu.idx]
+			if j.state == JobRunning {
+				j.cancel()
+				m.news = fmt.Sprintf("Cancelling job #%d", j.id)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m Model) jobsView() string {
+	if len(m.jobs) == 0 {
+		return m.styles.EmptyDir.Render("No jobs") + "\n"
+	}
+	items := make([]string, len(m.jobs))
+	for i, j := range m.jobs {
+		items[i] = j.String()
+	}
+	return m.menu.view(items, m.maxHeight, m.styles)
+}
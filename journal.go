@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const maxJournalEntries = 100
+
+type journalAction int
+
+const (
+	journalRecord journalAction = iota
+	journalUndo
+	journalRedo
+	journalNone
+)
+
+type fingerprint struct {
+	Files   int   `json:"files"`
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mod_time"`
+}
+
+type journalItem struct {
+	Src         string      `json:"src"`
+	Dest        string      `json:"dest"`
+	Fingerprint fingerprint `json:"fingerprint"`
+}
+
+type journalEntry struct {
+	ID        int64         `json:"id"`
+	Op        string        `json:"op"`
+	Items     []journalItem `json:"items"`
+	Overwrote bool          `json:"overwrote"`
+}
+
+type journal struct {
+	Entries []journalEntry `json:"entries"`
+	Pos     int            `json:"pos"`
+}
+
+func takeFingerprint(path string) fingerprint {
+	var fp fingerprint
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fp.Files++
+		if info.Mode().IsRegular() {
+			fp.Size += info.Size()
+		}
+		if mtime := info.ModTime().UnixNano(); fp.ModTime < mtime {
+			fp.ModTime = mtime
+		}
+		return nil
+	})
+	return fp
+}
+
+func journalPath() (string, error) {
+	return cachePath(JournalFile)
+}
+
+func loadJournal() (journal, error) {
+	var jr journal
+	path, err := journalPath()
+	if err != nil {
+		return jr, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return jr, nil
+	}
+	if err != nil {
+		return jr, err
+	}
+	if err := json.Unmarshal(data, &jr); err != nil {
+		return journal{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if jr.Pos < 0 || len(jr.Entries) < jr.Pos {
+		jr.Pos = len(jr.Entries)
+	}
+	return jr, nil
+}
+
+func (jr journal) save() error {
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(jr)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (jr *journal) push(e journalEntry) {
+	jr.Entries = append(jr.Entries[:jr.Pos], e)
+	if maxJournalEntries < len(jr.Entries) {
+		jr.Entries = jr.Entries[len(jr.Entries)-maxJournalEntries:]
+	}
+	jr.Pos = len(jr.Entries)
+}
+
+func (jr journal) find(id int64) int {
+	for i, e := range jr.Entries {
+		if e.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func recordOperation(op string, items []journalItem, overwrote bool) error {
+	if len(items) == 0 {
+		return nil
+	}
+	jr, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	jr.push(journalEntry{
+		ID:        time.Now().UnixNano(),
+		Op:        op,
+		Items:     items,
+		Overwrote: overwrote,
+	})
+	return jr.save()
+}
+
+func (j job) updateJournal(msg jobDoneMsg) error {
+	switch j.journal {
+	case journalRecord:
+		return recordOperation(j.kind.String(), msg.done, msg.overwrote)
+	case journalUndo, journalRedo:
+		if msg.err != nil || msg.cancelled {
+			return nil
+		}
+		jr, err := loadJournal()
+		if err != nil {
+			return err
+		}
+		i := jr.find(j.entryID)
+		if i < 0 {
+			return nil
+		}
+		if j.journal == journalUndo {
+			jr.Pos = i
+		} else {
+			jr.Entries[i].Items = msg.done
+			jr.Pos = i + 1
+		}
+		return jr.save()
+	}
+	return nil
+}
+
+func (e journalEntry) checkUndo() error {
+	if e.Overwrote {
+		return errors.New("it overwrote existing files")
+	}
+	dests := make(map[string]bool)
+	for _, item := range e.Items {
+		dests[item.Dest] = true
+	}
+	for _, item := range e.Items {
+		if takeFingerprint(item.Dest) != item.Fingerprint {
+			return fmt.Errorf("%s has changed since the %s", item.Dest, e.Op)
+		}
+		if e.Op != CopyJob.String() && !dests[item.Src] {
+			if _, err := os.Lstat(item.Src); err == nil {
+				return fmt.Errorf("%s already exists", item.Src)
+			}
+		}
+	}
+	return nil
+}
+
+func (e journalEntry) checkRedo() error {
+	srcs := make(map[string]bool)
+	for _, item := range e.Items {
+		srcs[item.Src] = true
+	}
+	for _, item := range e.Items {
+		if _, err := os.Lstat(item.Src); err != nil {
+			return fmt.Errorf("%s no longer exists", item.Src)
+		}
+		if _, err := os.Lstat(item.Dest); err == nil && !srcs[item.Dest] {
+			return fmt.Errorf("%s already exists", item.Dest)
+		}
+	}
+	return nil
+}
+
+func (m *Model) replayRenames(jr journal, i int, undo bool) tea.Cmd {
+	e := jr.Entries[i]
+	renames := make([]transfer, len(e.Items))
+	for k, item := range e.Items {
+		renames[k] = transfer{src: item.Src, dest: item.Dest}
+		if undo {
+			renames[k] = transfer{src: item.Dest, dest: item.Src}
+		}
+	}
+	if err := applyRenames(renames); err != nil {
+		m.news = fmt.Sprintf("Rename failed: %s", err)
+		return m.readDir(m.currDir)
+	}
+	for k := range e.Items {
+		e.Items[k].Fingerprint = takeFingerprint(e.Items[k].Dest)
+	}
+	jr.Pos = i + 1
+	m.news = fmt.Sprintf("Redid rename of %d file(s)", len(e.Items))
+	if undo {
+		jr.Pos = i
+		m.news = fmt.Sprintf("Undid rename of %d file(s)", len(e.Items))
+	}
+	if err := jr.save(); err != nil {
+		m.news = fmt.Sprintf("Journal error: %s", err)
+	}
+	return m.readDir(m.currDir)
+}
+
+func (m Model) journalBusy() bool {
+	for _, j := range m.runningJobs() {
+		if j.journal == journalUndo || j.journal == journalRedo {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Model) undo() tea.Cmd {
+	if m.journalBusy() {
+		m.news = "Wait for the previous undo or redo to finish"
+		return nil
+	}
+	jr, err := loadJournal()
+	if err != nil {
+		m.news = fmt.Sprintf("Undo failed: %s", err)
+		return nil
+	}
+	if jr.Pos == 0 {
+		m.news = "Nothing to undo"
+		return nil
+	}
+	e := jr.Entries[jr.Pos-1]
+	if err := e.checkUndo(); err != nil {
+		m.news = fmt.Sprintf("Cannot undo %s: %s", e.Op, err)
+		return nil
+	}
+	if e.Op == renameOp {
+		return m.replayRenames(jr, jr.Pos-1, true)
+	}
+	transfers := make([]transfer, len(e.Items))
+	for i, item := range e.Items {
+		transfers[i] = transfer{src: item.Dest, dest: item.Src}
+	}
+	kind := MoveJob
+	if e.Op == CopyJob.String() {
+		kind = DeleteJob
+	}
+	m.news = fmt.Sprintf("Undoing %s of %d file(s)", e.Op, len(e.Items))
+	cmd := m.startJob(kind, transfers, journalUndo)
+	m.jobs[len(m.jobs)-1].entryID = e.ID
+	return cmd
+}
+
+func (m *Model) redo() tea.Cmd {
+	if m.journalBusy() {
+		m.news = "Wait for the previous undo or redo to finish"
+		return nil
+	}
+	jr, err := loadJournal()
+	if err != nil {
+		m.news = fmt.Sprintf("Redo failed: %s", err)
+		return nil
+	}
+	if len(jr.Entries) <= jr.Pos {
+		m.news = "Nothing to redo"
+		return nil
+	}
+	e := jr.Entries[jr.Pos]
+	if err := e.checkRedo(); err != nil {
+		m.news = fmt.Sprintf("Cannot redo %s: %s", e.Op, err)
+		return nil
+	}
+	if e.Op == renameOp {
+		return m.replayRenames(jr, jr.Pos, false)
+	}
+	transfers := make([]transfer, len(e.Items))
+	for i, item := range e.Items {
+		transfers[i] = transfer{src: item.Src, dest: item.Dest}
+	}
+	kind := MoveJob
+	if e.Op == CopyJob.String() {
+		kind = CopyJob
+	}
+	m.news = fmt.Sprintf("Redoing %s of %d file(s)", e.Op, len(e.Items))
+	cmd := m.startJob(kind, transfers, journalRedo)
+	m.jobs[len(m.jobs)-1].entryID = e.ID
+	return cmd
+}
@@ -3,28 +3,80 @@ package main
 import "github.com/charmbracelet/bubbles/key"
 
 type KeyMap struct {
-	Up              key.Binding
-	Down            key.Binding
-	Right           key.Binding
-	Left            key.Binding
-	GoToTop         key.Binding
-	GoToBot         key.Binding
-	PgDn            key.Binding
-	PgUp            key.Binding
-	HalfPgDn        key.Binding
-	HalfPgUp        key.Binding
-	ToggleDots      key.Binding
-	GoHome          key.Binding
-	FilterOn        key.Binding
-	FilterOff       key.Binding
-	FilterAccept    key.Binding
-	ToggleSelect    key.Binding
-	ToggleSelectAll key.Binding
-	Yank            key.Binding
-	Cut             key.Binding
-	Paste           key.Binding
-	Quit            key.Binding
-	ForceQuit       key.Binding
+	Up                key.Binding
+	Down              key.Binding
+	Right             key.Binding
+	Left              key.Binding
+	GoToTop           key.Binding
+	GoToBot           key.Binding
+	PgDn              key.Binding
+	PgUp              key.Binding
+	HalfPgDn          key.Binding
+	HalfPgUp          key.Binding
+	ToggleDots        key.Binding
+	GoHome            key.Binding
+	FilterOn          key.Binding
+	FilterOff         key.Binding
+	FilterAccept      key.Binding
+	FilterCycle       key.Binding
+	ToggleSelect      key.Binding
+	ToggleSelectAll   key.Binding
+	Yank              key.Binding
+	Cut               key.Binding
+	Paste             key.Binding
+	Undo              key.Binding
+	Redo              key.Binding
+	LongListing       key.Binding
+	TogglePreview     key.Binding
+	MillerColumns     key.Binding
+	Find              key.Binding
+	Shell             key.Binding
+	NewTab            key.Binding
+	DualPane          key.Binding
+	SwitchPane        key.Binding
+	CopyToPane        key.Binding
+	MoveToPane        key.Binding
+	CloseTab          key.Binding
+	NextTab           key.Binding
+	PrevTab           key.Binding
+	JumpTab           key.Binding
+	OpenWith          key.Binding
+	Grep              key.Binding
+	GrepRegex         key.Binding
+	GrepEdit          key.Binding
+	JumpPrompt        key.Binding
+	JumpPrev          key.Binding
+	JumpNext          key.Binding
+	JumpAccept        key.Binding
+	JumpImport        key.Binding
+	HistoryBack       key.Binding
+	HistoryForward    key.Binding
+	PrevDir           key.Binding
+	ShowHistory       key.Binding
+	SetMark           key.Binding
+	JumpToMark        key.Binding
+	ShowMarks         key.Binding
+	MarkDelete        key.Binding
+	MarkRename        key.Binding
+	Sort              key.Binding
+	BulkRename        key.Binding
+	Trash             key.Binding
+	ShowTrash         key.Binding
+	TrashRestore      key.Binding
+	TrashDelete       key.Binding
+	TrashEmpty        key.Binding
+	ShowJobs          key.Binding
+	CancelJob         key.Binding
+	CloseMenu         key.Binding
+	Confirm           key.Binding
+	ConflictOverwrite key.Binding
+	ConflictSkip      key.Binding
+	ConflictKeepBoth  key.Binding
+	ConflictMerge     key.Binding
+	ConflictNewer     key.Binding
+	ConflictApplyAll  key.Binding
+	Quit              key.Binding
+	ForceQuit         key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
@@ -89,6 +141,10 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("enter", "tab", "shift+tab", "ctrl+k", "up", "ctrl+j", "down"),
 			key.WithHelp("enter", "apply filter"),
 		),
+		FilterCycle: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "cycle filter mode"),
+		),
 		ToggleSelect: key.NewBinding(
 			key.WithKeys(" "),
 			key.WithHelp("space", "toggle selection"),
@@ -109,6 +165,210 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("p"),
 			key.WithHelp("p", "paste"),
 		),
+		Undo: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "undo"),
+		),
+		Redo: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "redo"),
+		),
+		LongListing: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "toggle long listing"),
+		),
+		TogglePreview: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "toggle preview"),
+		),
+		MillerColumns: key.NewBinding(
+			key.WithKeys("M"),
+			key.WithHelp("M", "toggle miller columns"),
+		),
+		Find: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "find recursively"),
+		),
+		OpenWith: key.NewBinding(
+			key.WithKeys("O"),
+			key.WithHelp("O", "open with"),
+		),
+		DualPane: key.NewBinding(
+			key.WithKeys("|"),
+			key.WithHelp("|", "toggle dual pane"),
+		),
+		SwitchPane: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "switch pane"),
+		),
+		CopyToPane: key.NewBinding(
+			key.WithKeys("c", "f5"),
+			key.WithHelp("c/f5", "copy to other pane"),
+		),
+		MoveToPane: key.NewBinding(
+			key.WithKeys("C", "f6"),
+			key.WithHelp("C/f6", "move to other pane"),
+		),
+		NewTab: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "new tab"),
+		),
+		CloseTab: key.NewBinding(
+			key.WithKeys("ctrl+w"),
+			key.WithHelp("ctrl+w", "close tab"),
+		),
+		NextTab: key.NewBinding(
+			key.WithKeys("ctrl+n"),
+			key.WithHelp("ctrl+n", "next tab"),
+		),
+		PrevTab: key.NewBinding(
+			key.WithKeys("ctrl+p"),
+			key.WithHelp("ctrl+p", "previous tab"),
+		),
+		JumpTab: key.NewBinding(
+			key.WithKeys("alt+1", "alt+2", "alt+3", "alt+4", "alt+5", "alt+6", "alt+7", "alt+8", "alt+9"),
+			key.WithHelp("alt+1-9", "go to tab"),
+		),
+		Shell: key.NewBinding(
+			key.WithKeys("!"),
+			key.WithHelp("!", "run shell command"),
+		),
+		Grep: key.NewBinding(
+			key.WithKeys("F"),
+			key.WithHelp("F", "search file contents"),
+		),
+		GrepRegex: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "toggle regex"),
+		),
+		GrepEdit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "open in editor"),
+		),
+		JumpPrompt: key.NewBinding(
+			key.WithKeys("z"),
+			key.WithHelp("z", "jump to a frequent directory"),
+		),
+		JumpPrev: key.NewBinding(
+			key.WithKeys("up", "ctrl+k"),
+			key.WithHelp("up", "previous match"),
+		),
+		JumpNext: key.NewBinding(
+			key.WithKeys("down", "ctrl+j"),
+			key.WithHelp("down", "next match"),
+		),
+		JumpImport: key.NewBinding(
+			key.WithKeys("ctrl+o"),
+			key.WithHelp("ctrl+o", "import zoxide/z/autojump"),
+		),
+		JumpAccept: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "jump"),
+		),
+		HistoryBack: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "back"),
+		),
+		HistoryForward: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "forward"),
+		),
+		PrevDir: key.NewBinding(
+			key.WithKeys("-"),
+			key.WithHelp("-", "previous directory"),
+		),
+		ShowHistory: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "show history"),
+		),
+		SetMark: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "set mark"),
+		),
+		JumpToMark: key.NewBinding(
+			key.WithKeys("'"),
+			key.WithHelp("'", "jump to mark"),
+		),
+		ShowMarks: key.NewBinding(
+			key.WithKeys("B"),
+			key.WithHelp("B", "show marks"),
+		),
+		MarkDelete: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "delete mark"),
+		),
+		MarkRename: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "rename mark"),
+		),
+		Sort: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "sort options"),
+		),
+		BulkRename: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "bulk rename in $EDITOR"),
+		),
+		Trash: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "move to trash"),
+		),
+		ShowTrash: key.NewBinding(
+			key.WithKeys("T"),
+			key.WithHelp("T", "show trash"),
+		),
+		TrashRestore: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "restore from trash"),
+		),
+		TrashDelete: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "delete permanently"),
+		),
+		TrashEmpty: key.NewBinding(
+			key.WithKeys("E"),
+			key.WithHelp("E", "empty trash"),
+		),
+		ShowJobs: key.NewBinding(
+			key.WithKeys("J"),
+			key.WithHelp("J", "show jobs"),
+		),
+		CancelJob: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "cancel job"),
+		),
+		CloseMenu: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc/q", "close menu"),
+		),
+		Confirm: key.NewBinding(
+			key.WithKeys("enter", "y"),
+			key.WithHelp("enter/y", "confirm"),
+		),
+		ConflictOverwrite: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "overwrite"),
+		),
+		ConflictSkip: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "skip"),
+		),
+		ConflictKeepBoth: key.NewBinding(
+			key.WithKeys("k"),
+			key.WithHelp("k", "keep both"),
+		),
+		ConflictMerge: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "merge directories"),
+		),
+		ConflictNewer: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "overwrite if newer"),
+		),
+		ConflictApplyAll: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "apply to all conflicts"),
+		),
 		Quit: key.NewBinding(
 			key.WithKeys("q"),
 			key.WithHelp("q", "quit"),
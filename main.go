@@ -1,10 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -19,6 +21,11 @@ const (
 	CacheSubDir  string = "nav"
 	CacheFile    string = ".nav_d"
 	EnvCacheFile string = ".nav_env"
+	JournalFile  string = ".nav_journal"
+	MarksFile    string = ".nav_marks"
+	FrecencyFile string = ".nav_frecency"
+	ConfigSubDir string = "nav"
+	ConfigFile   string = "config"
 )
 
 var (
@@ -34,8 +41,11 @@ func nextID() int {
 }
 
 type readDirMsg struct {
-	id    int
-	files []os.DirEntry
+	id       int
+	path     string
+	files    []os.DirEntry
+	infos    map[string]os.FileInfo
+	linkDirs map[string]bool
 }
 
 func isDirAccessible(path string) bool {
@@ -48,13 +58,19 @@ func isDirAccessible(path string) bool {
 }
 
 func (m Model) readDir(path string) tea.Cmd {
+	return m.listDir(m.id, path, m.sort)
+}
+
+func (m Model) listDir(id int, path string, s sortSettings) tea.Cmd {
 	return func() tea.Msg {
 		dirEntries, err := os.ReadDir(path)
 		if err != nil {
 			return err
 		}
 		if m.showHidden {
-			return readDirMsg{id: m.id, files: dirEntries}
+			infos := readInfos(dirEntries)
+			sortEntries(dirEntries, infos, s)
+			return readDirMsg{id: id, path: path, files: dirEntries, infos: infos, linkDirs: readLinkDirs(path, infos)}
 		}
 		var filtered []os.DirEntry
 		for _, f := range dirEntries {
@@ -66,70 +82,141 @@ func (m Model) readDir(path string) tea.Cmd {
 				filtered = append(filtered, f)
 			}
 		}
-		return readDirMsg{id: m.id, files: filtered}
+		infos := readInfos(filtered)
+		sortEntries(filtered, infos, s)
+		return readDirMsg{id: id, path: path, files: filtered, infos: infos, linkDirs: readLinkDirs(path, infos)}
 	}
 }
 
 type Model struct {
-	files         []os.DirEntry
-	currDir       string
-	maxHeight     int
-	idx           int
-	keys          KeyMap
-	styles        Styles
-	min           int
-	max           int
-	pageDist      int
-	halfDist      int
-	showHidden    bool
-	lastFile      string
-	cursorSave    map[string]int
-	filter        FilterFunc
-	filterState   FilterState
-	filteredFiles filteredFiles
-	filterInput   textinput.Model
-	selection     map[string]mapset.Set
-	copyBuffer    []string
-	isCutting     bool
-	news          string
-	id            int
+	tab
+	maxHeight        int
+	width            int
+	keys             KeyMap
+	styles           Styles
+	pageDist         int
+	halfDist         int
+	selection        map[string]mapset.Set
+	copyBuffer       []string
+	isCutting        bool
+	news             string
+	jobs             []job
+	conflicts        []jobConflictMsg
+	conflictApplyAll bool
+	conflictPolicy   ConflictPolicy
+	trashEntries     []trashEntry
+	trashConfirm     string
+	renamePlan       renamePlan
+	defaultSort      sortSettings
+	longListing      bool
+	columns          []Column
+	relativeTime     bool
+	showPreview      bool
+	millerColumns    bool
+	watch            bool
+	watcher          *watcher
+	watchKey         string
+	markPending      markAction
+	marks            []mark
+	visited          []string
+	jumpInput        textinput.Model
+	jumpEntries      []frecencyEntry
+	jumpMatches      []frecencyEntry
+	find             finder
+	findDepth        int
+	grep             grepper
+	grepMaxSize      int
+	shellInput       textinput.Model
+	shellPause       bool
+	openers          []opener
+	openWithPath     string
+	openWithMatches  []opener
+	parentCol        column
+	childCol         column
+	previewPath      string
+	previewLines     []string
+	previewErr       error
+	overlay          Overlay
+	menu             menu
+	tabs             []tab
+	panes            []paneState
+	cdFile           string
+	selectionFile    string
+	fileFormat       OutputFormat
+	clipboardFormat  OutputFormat
+	stdoutFormat     OutputFormat
+	quitting         bool
+	pane             tab
+	dualPane         bool
+	paneRight        bool
+	tabIdx           int
 }
 
 func New() Model {
-	dir, err := filepath.Abs(".")
-	if err != nil {
-		log.Fatal(err)
+	return NewWithConfig(DefaultConfig())
+}
+
+func NewWithConfig(cfg Config) Model {
+	dir := cfg.Dir
+	if dir == "" {
+		var err error
+		dir, err = filepath.Abs(".")
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	t := newTab(dir, cfg.Sort, cfg.Filter, cfg.ShowHidden)
+	t.lastFile = cfg.File
+	if cfg.Query != "" {
+		t.filterInput.SetValue(cfg.Query)
+		t.filterState = FilterApplied
 	}
-	filterInput := textinput.New()
-	filterInput.Prompt = "/"
 	return Model{
-		currDir:     dir,
-		maxHeight:   0,
-		idx:         0,
-		keys:        DefaultKeyMap(),
-		styles:      DefaultStyles(),
-		min:         0,
-		max:         0,
-		pageDist:    37,
-		halfDist:    18,
-		showHidden:  false,
-		lastFile:    "",
-		cursorSave:  make(map[string]int),
-		filter:      DefaultFilter,
-		filterState: Unfiltered,
-		filterInput: filterInput,
-		selection:   make(map[string]mapset.Set),
-		copyBuffer:  make([]string, 0),
-		isCutting:   false,
-		news:        "",
-		id:          nextID(),
+		tab:             t,
+		cdFile:          cfg.CdFile,
+		selectionFile:   cfg.SelectionFile,
+		fileFormat:      cfg.FileFormat,
+		clipboardFormat: cfg.ClipboardFormat,
+		stdoutFormat:    cfg.StdoutFormat,
+		tabs:            make([]tab, 1),
+		panes:           make([]paneState, 1),
+		visited:         []string{dir},
+		maxHeight:       0,
+		keys:            cfg.Keys,
+		styles:          cfg.Styles,
+		pageDist:        cfg.PageDist,
+		halfDist:        cfg.HalfDist,
+		conflictPolicy:  cfg.Conflict,
+		defaultSort:     cfg.Sort,
+		longListing:     cfg.LongListing,
+		columns:         cfg.Columns,
+		relativeTime:    cfg.RelativeTime,
+		showPreview:     cfg.ShowPreview,
+		millerColumns:   cfg.MillerColumns,
+		watch:           cfg.Watch,
+		parentCol:       column{id: nextID()},
+		childCol:        column{id: nextID()},
+		jumpInput:       newJumpInput(),
+		findDepth:       cfg.FindDepth,
+		grepMaxSize:     cfg.GrepMaxSize,
+		shellInput:      newShellInput(),
+		shellPause:      cfg.ShellPause,
+		openers:         cfg.Openers,
+		selection:       make(map[string]mapset.Set),
+		copyBuffer:      make([]string, 0),
+		isCutting:       false,
+		news:            "",
+		overlay:         NoOverlay,
 	}
 }
 
-func (m *Model) refreshFiles() {
+func (m *tab) refreshFiles() {
 	if len(m.files)-1 < m.idx {
 		m.idx = len(m.files) - 1
 	}
+	if m.idx < 0 {
+		m.idx = 0
+	}
 	if m.lastFile == "" {
 		return
 	}
@@ -140,55 +227,141 @@ func (m *Model) refreshFiles() {
 		}
 	}
 	m.lastFile = ""
+	if m.max < m.idx {
+		m.min += m.idx - m.max
+		m.max = m.idx
+	}
+	if m.idx < m.min {
+		m.max -= m.min - m.idx
+		m.min = m.idx
+	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return m.readDir(m.currDir)
+	return tea.Batch(m.readDir(m.currDir), recordFrecency(m.currDir))
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	m = model.(Model)
+	m.showQueuedConflict()
+	return m, tea.Batch(cmd, m.updatePreview(), m.updateColumns(), m.updateWatches())
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case dirChangedMsg:
+		return m, m.handleDirChanged(msg)
+	case shellDoneMsg:
+		return m, m.handleShellDone(msg)
+	case grepBatchMsg:
+		return m, m.handleGrepBatch(msg)
+	case openMsg:
+		if msg.err != nil {
+			m.news = fmt.Sprintf("Open error: %s", msg.err)
+		}
+		return m, nil
+	case editorMsg:
+		if msg.err != nil {
+			m.news = fmt.Sprintf("Editor error: %s", msg.err)
+		}
+		return m, nil
+	case findBatchMsg:
+		return m, m.handleFindBatch(msg)
+	case frecencyMsg:
+		if msg.err != nil {
+			m.news = fmt.Sprintf("Frecency error: %s", msg.err)
+		}
+		return m, nil
+	case previewMsg:
+		if msg.path == m.previewPath {
+			m.previewLines = msg.lines
+			m.previewErr = msg.err
+		}
+		return m, nil
 	case FilterMatchesMsg:
 		m.filteredFiles = filteredFiles(msg)
+		m.filterErr = nil
+		return m, nil
+	case paneFilterMsg:
+		m.handlePaneFilterMsg(msg)
+		return m, nil
+	case filterErrorMsg:
+		m.filterErr = msg.err
 		return m, nil
+	case jobProgressMsg, jobDoneMsg:
+		return m, m.handleJobMsg(msg)
+	case jobConflictMsg:
+		return m, m.handleConflictMsg(msg)
+	case bulkRenameMsg:
+		return m, m.handleBulkRenameMsg(msg)
 	case tea.KeyMsg:
 		if key.Matches(msg, m.keys.ForceQuit) {
 			return m, tea.Quit
 		}
 	case tea.WindowSizeMsg:
 		m.maxHeight = msg.Height - HeightBuffer
+		m.width = msg.Width
+		m.previewPath = ""
 		m.max = m.maxHeight
+		m.pane.max = m.pane.min + m.maxHeight
 	case readDirMsg:
+		if m.dualPane && msg.id == m.pane.id {
+			return m, m.handlePaneMsg(msg)
+		}
 		if msg.id != m.id {
+			m.handleColumnMsg(msg)
 			break
 		}
 		m.files = msg.files
-		m.max = m.maxHeight
+		m.infos = msg.infos
+		m.linkDirs = msg.linkDirs
+		m.previewPath = ""
+		m.parentCol.loaded = false
+		m.childCol.loaded = false
+		m.max = m.min + m.maxHeight
 		m.refreshFiles()
+		if m.filterState == FilterApplied {
+			return m, filterFiles(m)
+		}
 	}
 
+	if msg, ok := msg.(tea.KeyMsg); ok && m.markPending != markNone {
+		return m.pendingMarkMode(msg)
+	}
+	switch m.overlay {
+	case JobsOverlay:
+		return m.jobsMode(msg)
+	case ConflictOverlay:
+		return m.conflictMode(msg)
+	case TrashOverlay:
+		return m.trashMode(msg)
+	case RenameOverlay:
+		return m.renameMode(msg)
+	case SortOverlay:
+		return m.sortMode(msg)
+	case MarksOverlay:
+		return m.marksMode(msg)
+	case HistoryOverlay:
+		return m.historyMode(msg)
+	case JumpOverlay:
+		return m.jumpMode(msg)
+	case FindOverlay:
+		return m.findMode(msg)
+	case GrepOverlay:
+		return m.grepMode(msg)
+	case ShellOverlay:
+		return m.shellMode(msg)
+	case OpenWithOverlay:
+		return m.openWithMode(msg)
+	}
 	if m.filterState == Filtering {
 		return m.filterMode(msg)
 	}
 	return m.normalMode(msg)
 }
 
-func (m Model) View() string {
-	currPath, err := filepath.Abs(m.currDir)
-	if err != nil {
-		currPath = fmt.Sprintf("Error displaying absolute path: %s", err)
-	}
-	currPath = m.styles.Path.Render(currPath)
-
-	if len(m.files) == 0 {
-		return currPath + "\n\n" + m.styles.EmptyDir.String()
-	}
-
-	isRoot := m.currDir == "/"
-	if !isRoot {
-		currPath += m.styles.Path.Render("/")
-	}
-
+func (m Model) listing() (string, string) {
 	files := ""
 	hovered := ""
 	if m.filterState == Unfiltered || m.filterState == FilterApplied {
@@ -198,6 +371,10 @@ func (m Model) View() string {
 		} else {
 			filesIterate = m.filteredFiles.filteredFilesAsDirEntries()
 		}
+		var details []string
+		if m.longListing && m.min < len(filesIterate) {
+			details = m.detailRows(filesIterate[m.min:min(m.max+1, len(filesIterate))])
+		}
 		for i, f := range filesIterate {
 			if i < m.min {
 				continue
@@ -206,60 +383,171 @@ func (m Model) View() string {
 				break
 			}
 
-			info, err := f.Info()
+			info, err := m.fileInfo(f)
 			if err != nil {
 				files += fmt.Sprintf("Error reading file info: %s\n", err)
 				continue
 			}
 			isSymlink := info.Mode()&os.ModeSymlink != 0
 
-			file := f.Name()
+			var matches []int
+			if m.filterState == FilterApplied {
+				matches = m.filteredFiles[i].matches
+			}
+			style := m.styles.Regular
+			suffix := ""
 			switch {
-			case i == m.idx && f.IsDir():
-				hovered = m.styles.PathEnd.Render(file)
-				file = m.styles.DirHover.Render(file + "/")
-			case i == m.idx && isSymlink:
-				hovered = m.styles.PathEnd.Render(file)
-				target, err := filepath.EvalSymlinks(filepath.Join(m.currDir, file))
-				if err != nil {
-					file = m.styles.SymHover.Render(file + " -> " + fmt.Sprintf("%s", err))
-					break
-				}
-				file = m.styles.SymHover.Render(file + " -> " + target)
 			case f.IsDir():
-				file = m.styles.Directory.Render(file + "/")
+				style = m.styles.Directory
+				suffix = "/"
 			case isSymlink:
-				target, err := filepath.EvalSymlinks(filepath.Join(m.currDir, file))
+				style = m.styles.Symlink
+				target, err := filepath.EvalSymlinks(filepath.Join(m.currDir, f.Name()))
 				if err != nil {
-					file = m.styles.Symlink.Render(file + " -> " + fmt.Sprintf("%s", err))
+					suffix = " -> " + fmt.Sprintf("%s", err)
 					break
 				}
-				file = m.styles.Symlink.Render(file + " -> " + target)
-			case i == m.idx:
-				hovered = m.styles.PathEnd.Render(file)
-				file = m.styles.Hover.Render(file)
+				suffix = " -> " + target
+			}
+			if i == m.idx {
+				hovered = m.styles.PathEnd.Render(f.Name())
+				switch {
+				case f.IsDir():
+					style = m.styles.DirHover
+				case isSymlink:
+					style = m.styles.SymHover
+				default:
+					style = m.styles.Hover
+				}
+			}
+			file := m.highlight(f.Name(), matches, style) + style.Render(suffix)
+			if matches == nil {
+				file = style.Render(f.Name() + suffix)
 			}
 			fileSet, ok := m.selection[m.currDir]
 			if ok && fileSet.Contains(f.Name()) {
 				file = m.styles.Selected.Render(file)
 			}
+			if details != nil {
+				file = details[i-m.min] + file
+			}
 			files += file + "\n"
 		}
 	} else {
 		for _, f := range m.filteredFiles {
-			files += m.styles.Filter.Render(f.file.Name()) + "\n"
+			files += m.highlight(f.file.Name(), f.matches, m.styles.Filter) + "\n"
 		}
 	}
-	filterBar := "\n\n"
-	if m.filterState == Filtering || m.filterState == FilterApplied {
-		filterBar = "\n" + m.styles.Filter.Render(m.filterInput.View()) + "\n"
+	return files, hovered
+}
+
+func (m Model) View() string {
+	currPath, err := filepath.Abs(m.currDir)
+	if err != nil {
+		currPath = fmt.Sprintf("Error displaying absolute path: %s", err)
 	}
+	currPath = m.tabBar() + m.styles.Path.Render(currPath)
 	news := m.styles.News.Render(m.news)
+	if status := m.jobStatus(); status != "" {
+		news += "\n" + m.styles.News.Render(status)
+	}
+
+	switch m.overlay {
+	case JobsOverlay:
+		return currPath + "\n\n" + m.jobsView() + news + "\n"
+	case ConflictOverlay:
+		return currPath + "\n\n" + m.conflictView() + news + "\n"
+	case TrashOverlay:
+		return currPath + "\n\n" + m.trashView() + news + "\n"
+	case RenameOverlay:
+		return currPath + "\n\n" + m.renameView() + news + "\n"
+	case SortOverlay:
+		return currPath + "\n\n" + m.sortView() + news + "\n"
+	case MarksOverlay:
+		return currPath + "\n\n" + m.marksView() + news + "\n"
+	case HistoryOverlay:
+		return currPath + "\n\n" + m.historyView() + news + "\n"
+	case OpenWithOverlay:
+		return currPath + "\n\n" + m.openWithView() + news + "\n"
+	case JumpOverlay:
+		return currPath + "\n\n" + m.jumpView() + news + "\n"
+	case FindOverlay:
+		return currPath + "\n\n" + m.findView() + news + "\n"
+	case GrepOverlay:
+		return currPath + "\n\n" + m.grepView() + news + "\n"
+	}
+
+	if len(m.files) == 0 && !m.dualPane {
+		shellBar := "\n\n"
+		if m.overlay == ShellOverlay {
+			shellBar = "\n" + m.shellInput.View() + "\n"
+		}
+		return currPath + shellBar + m.styles.EmptyDir.String() + "\n" + news + "\n"
+	}
+
+	isRoot := m.currDir == "/"
+	if !isRoot {
+		currPath += m.styles.Path.Render("/")
+	}
+
+	files, hovered := m.listing()
+	filterBar := "\n\n"
+	if m.overlay == ShellOverlay {
+		filterBar = "\n" + m.shellInput.View() + "\n"
+	} else if m.filterState == Filtering || m.filterState == FilterApplied {
+		filterBar = "\n" + m.styles.Filter.Render(m.filterInput.View())
+		if m.filterErr != nil {
+			filterBar += "  " + m.styles.InaccessibleDir.Render(m.filterErr.Error())
+		}
+		filterBar += "\n"
+	}
+	if m.dualPane {
+		return m.tabBar() + m.dualView(hovered, filterBar, strings.TrimSuffix(files, "\n")) + "\n" + news + "\n"
+	}
+	if m.millerColumns {
+		files = m.millerView(strings.TrimSuffix(files, "\n")) + "\n"
+	} else if m.showPreview {
+		files = m.previewView(strings.TrimSuffix(files, "\n")) + "\n"
+	}
 	return currPath + hovered + filterBar + files + news + "\n"
 }
 
 func main() {
-	if _, err := tea.NewProgram(New()).Run(); err != nil {
+	opts, err := parseFlags(os.Args[1:], os.Stderr)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	if opts.version {
+		fmt.Println("nav", version)
+		return
+	}
+	if opts.importFrecency != "" {
+		n, err := importFrecency(opts.importFrecency)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Imported %d directories\n", n)
+		return
+	}
+	cfg, err := opts.loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := opts.apply(&cfg); err != nil {
 		log.Fatal(err)
 	}
+	var programOpts []tea.ProgramOption
+	if cfg.StdoutFormat.kind != OutputNone {
+		programOpts = append(programOpts, tea.WithOutput(os.Stderr))
+	}
+	model, err := tea.NewProgram(NewWithConfig(cfg), programOpts...).Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if m := model.(Model); m.quitting && 0 < len(m.selection) {
+		fmt.Print(m.selectionOutput(m.stdoutFormat))
+	}
 }
@@ -1,6 +1,23 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	mapset "github.com/deckarep/golang-set"
+)
 
 func TestIsDirAccessible(t *testing.T) {
 	if !isDirAccessible("/tmp") {
@@ -10,3 +27,864 @@ func TestIsDirAccessible(t *testing.T) {
 		t.Error("Expected /tmp/foobar to be inaccessible")
 	}
 }
+
+func TestParseConfig(t *testing.T) {
+	input := `# comment
+set page_dist 40
+set show_hidden
+map Quit Q ctrl+q
+style Directory fg=4 bold=false
+`
+	cfg, err := parseConfig(strings.NewReader(input), "config")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if cfg.PageDist != 40 || cfg.HalfDist != 18 || !cfg.ShowHidden {
+		t.Errorf("Unexpected options: %+v", cfg)
+	}
+	if keys := cfg.Keys.Quit.Keys(); len(keys) != 2 || keys[0] != "Q" {
+		t.Errorf("Expected Quit to be rebound, got %v", keys)
+	}
+	if cfg.Styles.Directory.GetBold() {
+		t.Error("Expected Directory style not to be bold")
+	}
+
+	_, err = parseConfig(strings.NewReader("set page_dist 40\nstyle Nope fg=1\n"), "config")
+	if err == nil || !strings.Contains(err.Error(), "config:2:") {
+		t.Errorf("Expected error naming line 2, got %v", err)
+	}
+}
+
+func TestKeepBothName(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"report.pdf", "report (1).pdf"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := map[string]string{
+		"report.pdf":    "report (2).pdf",
+		"backup.tar.gz": "backup (1).tar.gz",
+		".bashrc":       ".bashrc (1)",
+		"no_extension":  "no_extension (1)",
+	}
+	for name, want := range tests {
+		got, err := keepBothName(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(got) != want {
+			t.Errorf("keepBothName(%q) = %q, expected %q", name, filepath.Base(got), want)
+		}
+	}
+}
+
+func TestRenameSwap(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := planRenames(dir, []string{"a", "b"}, []string{"c", "b"}); err == nil {
+		t.Error("Expected renaming onto an existing file to fail")
+	}
+	if _, err := planRenames(dir, []string{"a", "b"}, []string{"x", "x"}); err == nil {
+		t.Error("Expected duplicate names to fail")
+	}
+
+	plan, err := planRenames(dir, []string{"a", "b", "c"}, []string{"b", "a", "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.cycles) != 2 {
+		t.Errorf("Expected a->b and b->a to be detected as a cycle, got %v", plan.cycles)
+	}
+	if err := applyRenames(plan.renames); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"a": "b", "b": "a", "d": "c"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != want {
+			t.Errorf("Expected %s to contain %q, got %q (%v)", name, want, got, err)
+		}
+	}
+
+	failing := []transfer{
+		{src: filepath.Join(dir, "a"), dest: filepath.Join(dir, "e")},
+		{src: filepath.Join(dir, "b"), dest: filepath.Join(dir, "missing", "f")},
+	}
+	if err := applyRenames(failing); err == nil {
+		t.Error("Expected renaming into a missing directory to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a")); err != nil {
+		t.Errorf("Expected a to be restored after the failed rename: %v", err)
+	}
+
+	plan, err = planRenames(dir, []string{"a", "b"}, []string{"g", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "g"), []byte("precious"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyRenames(plan.renames); err == nil {
+		t.Error("Expected renaming onto a file created after planning to fail")
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "g")); err != nil || string(got) != "precious" {
+		t.Errorf("Expected g to be kept, got %q (%v)", got, err)
+	}
+	if err := checkedRename(filepath.Join(dir, "a"), filepath.Join(dir, "g")); err == nil {
+		t.Error("Expected the fallback rename to refuse replacing g")
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	names := []string{"file10", "file2", "file1", "file02b", "a", "file2a"}
+	sort.SliceStable(names, func(i, j int) bool {
+		return naturalLess(names[i], names[j])
+	})
+	want := []string{"a", "file1", "file2", "file2a", "file02b", "file10"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+}
+
+func TestParseZoxide(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(3))
+	binary.Write(&buf, binary.LittleEndian, uint64(1))
+	binary.Write(&buf, binary.LittleEndian, uint64(len("/tmp/proj")))
+	buf.WriteString("/tmp/proj")
+	binary.Write(&buf, binary.LittleEndian, 12.5)
+	binary.Write(&buf, binary.LittleEndian, uint64(1700000000))
+
+	entries, err := parseZoxide(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := frecencyEntry{path: "/tmp/proj", rank: 12.5, last: 1700000000}
+	if len(entries) != 1 || entries[0] != want {
+		t.Errorf("Expected [%v], got %v", want, entries)
+	}
+	if _, err := parseZoxide(buf.Bytes()[:buf.Len()-4]); err == nil {
+		t.Error("Expected an error for a truncated database")
+	}
+}
+
+func TestFrecencyImport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "cache"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("_Z_DATA", filepath.Join(home, "z"))
+	if err := os.WriteFile(filepath.Join(home, "z"), []byte("/tmp/proj|10|1700000000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	odd := "/tmp/a\tb\nc"
+	if msg := recordFrecency(odd)(); msg.(frecencyMsg).err != nil {
+		t.Fatal(msg)
+	}
+	m := New()
+	m.importForeignFrecency()
+	m.importForeignFrecency()
+	entries, err := loadFrecency()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranks := make(map[string]float64)
+	for _, e := range entries {
+		ranks[e.path] = e.rank
+	}
+	if len(entries) != 2 || ranks["/tmp/proj"] != 10 || ranks[odd] != 1 {
+		t.Errorf("Expected one import of /tmp/proj and one visit of %q, got %v", odd, entries)
+	}
+}
+
+func TestFilterModes(t *testing.T) {
+	targets := []string{"Makefile", "main.go", "main_test.go", "README.md"}
+	tests := []struct {
+		filter FilterFunc
+		term   string
+		want   []int
+	}{
+		{SubstringFilter, "ma", []int{0, 1, 2}},
+		{SubstringFilter, "Ma", []int{0}},
+		{GlobFilter, "*.go", []int{1, 2}},
+		{RegexFilter, `^main(_test)?\.go$`, []int{1, 2}},
+		{PrefixFilter, "main", []int{1, 2}},
+	}
+	for _, test := range tests {
+		ranks, err := test.filter(test.term, targets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, r := range ranks {
+			got = append(got, r.Index)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("Filter %q: expected %v, got %v", test.term, test.want, got)
+		}
+	}
+	if _, err := RegexFilter("main(", targets); err == nil {
+		t.Error("Expected an error for an invalid regex")
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	dir := t.TempDir()
+	rules := "# comment\n*.log\nbuild/\n/root.txt\ndocs/*.md\n!keep.log\n"
+	if err := os.WriteFile(filepath.Join(dir, ".navignore"), []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ignore := readIgnoreFile(dir, ".navignore")
+	if len(ignore) != 4 {
+		t.Fatalf("Expected 4 rules, got %v", ignore)
+	}
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.log", false, true},
+		{"sub/a.log", false, true},
+		{"keep.log", false, true},
+		{"a.txt", false, false},
+		{"build", true, true},
+		{"sub/build", true, true},
+		{"build", false, false},
+		{"root.txt", false, true},
+		{"sub/root.txt", false, false},
+		{"docs/x.md", false, true},
+		{"sub/docs/x.md", false, false},
+		{"docs/sub/x.md", false, false},
+	}
+	for _, test := range tests {
+		if got := isIgnored(ignore, filepath.Join(dir, test.path), test.isDir); got != test.want {
+			t.Errorf("%s: expected %v, got %v", test.path, test.want, got)
+		}
+	}
+}
+
+func TestOpeners(t *testing.T) {
+	dir := t.TempDir()
+	png := filepath.Join(dir, "photo.PNG")
+	if err := os.WriteFile(png, []byte("\x89PNG\r\n\x1a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var openers []opener
+	for _, line := range []string{
+		"ext:png gui feh",
+		"mime:image/* gui sxiv %f",
+		"glob:notes.* less",
+	} {
+		o, err := parseOpener(strings.Fields(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		openers = append(openers, o)
+	}
+	if got := matchingOpeners(openers, png); len(got) != 2 || got[0].command != "feh" || got[1].command != "sxiv %f" {
+		t.Errorf("Expected feh and sxiv for %s, got %v", png, got)
+	}
+	if got := matchingOpeners(openers, notes); len(got) != 2 || got[0].command != "less" || got[1].command != "" {
+		t.Errorf("Expected less and $EDITOR for %s, got %v", notes, got)
+	}
+	if _, err := parseOpener([]string{"type:png", "feh"}); err == nil {
+		t.Error("Expected an error for an unknown matcher")
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := parseFlags([]string{"-hidden", "-sort", "size", "-cd-file", "/tmp/d", file}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	if err := opts.apply(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.ShowHidden || cfg.Sort.mode != SortSize || cfg.CdFile != "/tmp/d" {
+		t.Errorf("Flags not applied: %+v", opts)
+	}
+	if cfg.Dir != dir || cfg.File != "notes.txt" {
+		t.Errorf("Expected %s and notes.txt, got %s and %s", dir, cfg.Dir, cfg.File)
+	}
+	opts, err = parseFlags(nil, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = DefaultConfig()
+	cfg.ShowHidden = true
+	if err := opts.apply(&cfg); err != nil || !cfg.ShowHidden || cfg.Dir != "" {
+		t.Errorf("Expected unset flags to keep the config, got %+v", cfg)
+	}
+	opts, err = parseFlags([]string{"-filter", "substring", "-query", "notes", dir}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = DefaultConfig()
+	if err := opts.apply(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	m := NewWithConfig(cfg)
+	if m.filterState != FilterApplied || m.filterInput.Value() != "notes" {
+		t.Errorf("Expected the filter notes to be applied, got %q (state %v)", m.filterInput.Value(), m.filterState)
+	}
+	_, cmd := m.update(m.readDir(m.currDir)())
+	if cmd == nil {
+		t.Fatal("Expected the filter to run once the directory is read")
+	}
+	if ff, ok := cmd().(FilterMatchesMsg); !ok || len(ff) != 1 || ff[0].file.Name() != "notes.txt" {
+		t.Errorf("Expected notes.txt to match, got %v", ff)
+	}
+	if _, err := parseFlags([]string{"a", "b"}, io.Discard); err == nil {
+		t.Error("Expected an error for too many arguments")
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	paths := []string{"/home/u/b c.txt", "/home/u/it's", "/home/u/a \"q\"\nb"}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"space", "/home/u/a \"q\"\nb /home/u/b c.txt /home/u/it's"},
+		{"newline", "/home/u/a \"q\"\nb\n/home/u/b c.txt\n/home/u/it's\n"},
+		{"nul", "/home/u/a \"q\"\nb\x00/home/u/b c.txt\x00/home/u/it's\x00"},
+		{"shell", `'/home/u/a "q"` + "\n" + `b' '/home/u/b c.txt' '/home/u/it'\''s'`},
+		{"json,relative", `["a \"q\"\nb","b c.txt","it's"]`},
+		{"none", ""},
+	}
+	for _, test := range tests {
+		f, err := parseOutputFormat(test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.format(paths, "/home/u"); got != test.want {
+			t.Errorf("Format %s: expected %q, got %q", test.format, test.want, got)
+		}
+	}
+	if cfg := DefaultConfig(); cfg.FileFormat.kind != OutputSpace || cfg.ClipboardFormat.kind != OutputSpace {
+		t.Errorf("Expected space to stay the default format, got %+v", cfg.FileFormat)
+	}
+	if _, err := parseOutputFormat("json,absolute"); err == nil {
+		t.Error("Expected an error for an unknown modifier")
+	}
+
+	dir := t.TempDir()
+	m := NewWithConfig(Config{Dir: dir, CdFile: filepath.Join(dir, "d"), SelectionFile: filepath.Join(dir, "s")})
+	if err := os.WriteFile(m.selectionFile, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.quitRoutine()
+	if data, err := os.ReadFile(m.selectionFile); err != nil || len(data) != 0 {
+		t.Errorf("Expected an empty selection to truncate the selection file, got %q (%v)", data, err)
+	}
+}
+
+func TestIsWithin(t *testing.T) {
+	tests := []struct {
+		path, dir string
+		want      bool
+	}{
+		{"/a/b", "/a", true},
+		{"/a", "/a", true},
+		{"/a/..b", "/a", true},
+		{"/ab", "/a", false},
+		{"/", "/a", false},
+	}
+	for _, test := range tests {
+		if got := isWithin(test.path, test.dir); got != test.want {
+			t.Errorf("isWithin(%q, %q): expected %v, got %v", test.path, test.dir, test.want, got)
+		}
+	}
+}
+
+func TestMarksRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	want := []mark{
+		{letter: "a", path: "/tmp/tab\there", file: "new\nline"},
+		{letter: "b", path: `/tmp/"quoted"`, file: ""},
+	}
+	if err := saveMarks(append([]mark(nil), want...)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadMarks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestHighlightMultibyte(t *testing.T) {
+	m := New()
+	m.styles.Match = lipgloss.NewStyle().Padding(0, 1)
+	names := []string{"crème brûlée.txt"}
+	ranks, err := DefaultFilter("brû", names)
+	if err != nil || len(ranks) != 1 {
+		t.Fatalf("Expected one match, got %v (%v)", ranks, err)
+	}
+	got := m.highlight(names[0], ranks[0].MatchedIndexes, lipgloss.NewStyle())
+	if want := "crème  brû lée.txt"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	ranks, err = SubstringFilter("èm", names)
+	if err != nil || len(ranks) != 1 {
+		t.Fatalf("Expected one match, got %v (%v)", ranks, err)
+	}
+	got = m.highlight(names[0], ranks[0].MatchedIndexes, lipgloss.NewStyle())
+	if want := "cr èm e brûlée.txt"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestPanePerTab(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	m := NewWithConfig(Config{Dir: a})
+	m.toggleDualPane()
+	m.pane.currDir = b
+	m.switchPane()
+	m.newTab()
+	if m.paneRight || m.pane.currDir != m.currDir {
+		t.Errorf("Expected a fresh pane in the new tab, got %s (right %v)", m.pane.currDir, m.paneRight)
+	}
+	m.switchTab(0)
+	if !m.paneRight || m.currDir != b || m.pane.currDir != a {
+		t.Errorf("Expected the first tab's panes back, got %s and %s (right %v)", m.currDir, m.pane.currDir, m.paneRight)
+	}
+}
+
+func runJob(m *Model, cmd tea.Cmd) {
+	for cmd != nil {
+		msg := cmd()
+		cmd = m.handleJobMsg(msg)
+		if _, ok := msg.(jobDoneMsg); ok {
+			return
+		}
+	}
+}
+
+func TestPasteKeepsCutOnFailure(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	src, dest := t.TempDir(), t.TempDir()
+	moved := filepath.Join(src, "a")
+	if err := os.WriteFile(moved, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(src, "missing")
+	m := NewWithConfig(Config{Dir: dest})
+	m.copyBuffer = []string{moved, missing}
+	m.isCutting = true
+	runJob(&m, m.paste())
+	if !m.isCutting || len(m.copyBuffer) != 1 || m.copyBuffer[0] != missing {
+		t.Errorf("Expected only the failed path to stay cut, got %v (cutting %v)", m.copyBuffer, m.isCutting)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "a")); err != nil {
+		t.Error(err)
+	}
+}
+
+func otherDeviceDir(t *testing.T) string {
+	dir, err := os.MkdirTemp("/dev/shm", "nav")
+	if err != nil {
+		t.Skip("no second filesystem:", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if same, err := sameDevice(dir, t.TempDir()); err != nil || same {
+		t.Skip("/dev/shm is on the same filesystem")
+	}
+	return dir
+}
+
+func TestMoveAcrossDevices(t *testing.T) {
+	src, dest := t.TempDir(), otherDeviceDir(t)
+	tree := filepath.Join(src, "A")
+	if err := os.Mkdir(tree, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tree, "real"), []byte("real"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{"rel": "real", "broken": "missing"} {
+		if err := os.Symlink(target, filepath.Join(tree, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &copier{ctx: context.Background(), kind: MoveJob, msgs: make(chan tea.Msg, 1)}
+	if err := c.move(tree, filepath.Join(dest, "A")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(tree); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got %v", tree, err)
+	}
+	for name, want := range map[string]string{"rel": "real", "broken": "missing"} {
+		if got, err := os.Readlink(filepath.Join(dest, "A", name)); err != nil || got != want {
+			t.Errorf("Expected %s -> %s, got %s (%v)", name, want, got, err)
+		}
+	}
+
+	tree = filepath.Join(src, "B")
+	if err := os.Mkdir(tree, 0755); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("unix", filepath.Join(tree, "sock"))
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	if err := c.move(tree, filepath.Join(dest, "B")); err == nil {
+		t.Error("Expected moving a socket to fail")
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "B")); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial copy to be removed, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(tree, "sock")); err != nil {
+		t.Errorf("Expected the source to be kept, got %v", err)
+	}
+}
+
+func TestJournalUndoRedo(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	src, dest := t.TempDir(), t.TempDir()
+	for _, p := range []string{"A/x", "b"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(src, p)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(src, p), []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dest, "A"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m := NewWithConfig(Config{Dir: dest, Conflict: MergePolicy})
+	m.copyBuffer = []string{filepath.Join(src, "A")}
+	m.isCutting = true
+	runJob(&m, m.paste())
+	if _, err := os.Lstat(filepath.Join(src, "A")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the merged directory to be removed, got %v", err)
+	}
+	runJob(&m, m.undo())
+	if data, err := os.ReadFile(filepath.Join(src, "A", "x")); err != nil || string(data) != "A/x" {
+		t.Errorf("Expected the undo to restore A/x, got %q (%v)", data, err)
+	}
+	runJob(&m, m.redo())
+	if _, err := os.Lstat(filepath.Join(dest, "A", "x")); err != nil {
+		t.Errorf("Expected the redo to move A/x again, got %v", err)
+	}
+
+	m.copyBuffer = []string{filepath.Join(src, "b")}
+	m.isCutting = false
+	runJob(&m, m.paste())
+	if err := os.WriteFile(filepath.Join(dest, "b"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cmd := m.undo(); cmd != nil || !strings.Contains(m.news, "has changed since") {
+		t.Errorf("Expected the undo to be refused, got %q", m.news)
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "b")); err != nil {
+		t.Errorf("Expected the changed copy to be kept, got %v", err)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a b%.txt")
+	c := &copier{ctx: context.Background(), kind: TrashJob, msgs: make(chan tea.Msg, 1)}
+	for i := 0; i < 2; i++ {
+		if err := os.WriteFile(path, []byte{byte(i)}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.trash(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := trashDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(trashInfoPath(home, "a b%.txt"))
+	if err != nil || !strings.Contains(string(data), "Path="+strings.ReplaceAll(dir, " ", "%20")+"/a%20b%25.txt\n") {
+		t.Errorf("Expected an escaped Path, got %q (%v)", data, err)
+	}
+	entries, err := listTrashDir(home, "")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Expected two entries, got %v (%v)", entries, err)
+	}
+	for _, e := range entries {
+		if e.path != path || e.dir != home || e.deleted.IsZero() {
+			t.Errorf("Unexpected entry %+v", e)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(home, "files", numberedName("a b%.txt", 1))); err != nil {
+		t.Errorf("Expected the second file to get a numbered name, got %v", err)
+	}
+
+	if err := c.restore(filepath.Join(home, "files", "a b%.txt"), path); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, []byte{0}) {
+		t.Errorf("Expected the first file to be restored, got %v (%v)", data, err)
+	}
+	if err := purgeTrashed(filepath.Join(home, "files", numberedName("a b%.txt", 1))); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := listTrashDir(home, ""); err != nil || len(entries) != 0 {
+		t.Errorf("Expected an empty trash, got %v (%v)", entries, err)
+	}
+
+	other := otherDeviceDir(t)
+	top, err := topDir(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topTrash := filepath.Join(top, ".Trash-"+strconv.Itoa(os.Getuid()))
+	if _, err := os.Lstat(topTrash); err != nil {
+		t.Cleanup(func() { os.RemoveAll(topTrash) })
+	}
+	path = filepath.Join(other, "x")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.trash(path); err != nil {
+		t.Fatal(err)
+	}
+	rel, _ := filepath.Rel(top, path)
+	if data, err := os.ReadFile(trashInfoPath(topTrash, "x")); err != nil || !strings.Contains(string(data), "Path="+rel+"\n") {
+		t.Errorf("Expected a Path relative to %s, got %q (%v)", top, data, err)
+	}
+	e, err := parseTrashInfo(trashInfoPath(topTrash, "x"), top)
+	if err != nil || e.path != path {
+		t.Fatalf("Expected %s in %s, got %+v (%v)", path, topTrash, e, err)
+	}
+	if err := c.restore(filepath.Join(topTrash, "files", "x"), e.path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(path); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	root := t.TempDir()
+	a, b, c := filepath.Join(root, "a"), filepath.Join(root, "b"), filepath.Join(root, "c")
+	for _, dir := range []string{a, b, c} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := NewWithConfig(Config{Dir: root})
+	m.changeDir(a, "")
+	m.changeDir(b, "")
+	m.changeDir(c, "")
+	m.historyBack()
+	m.historyBack()
+	if m.currDir != a || m.prevDir != b {
+		t.Errorf("Expected two steps back to reach %s from %s, got %s from %s", a, b, m.currDir, m.prevDir)
+	}
+	m.historyForward()
+	if m.currDir != b {
+		t.Errorf("Expected forward to reach %s, got %s", b, m.currDir)
+	}
+
+	if err := os.Remove(c); err != nil {
+		t.Fatal(err)
+	}
+	m.historyForward()
+	if m.currDir != b || len(m.history) != 3 || !strings.Contains(m.news, "no longer exists") {
+		t.Errorf("Expected the removed directory to be dropped, got %s and %v (%q)", m.currDir, m.history, m.news)
+	}
+	m.historyForward()
+	if !strings.Contains(m.news, "newest") {
+		t.Errorf("Expected to be at the newest directory, got %q", m.news)
+	}
+
+	m.toggleDir()
+	if m.currDir != a || m.prevDir != b {
+		t.Errorf("Expected toggle to return to %s, got %s", a, m.currDir)
+	}
+	m.toggleDir()
+	if m.currDir != b || m.prevDir != a {
+		t.Errorf("Expected toggle to return to %s, got %s", b, m.currDir)
+	}
+	if want := []string{root, a, b, a, b}; fmt.Sprint(m.history) != fmt.Sprint(want) {
+		t.Errorf("Expected history %v, got %v", want, m.history)
+	}
+	for range m.history {
+		m.historyBack()
+	}
+	if m.currDir != root || !strings.Contains(m.news, "oldest") {
+		t.Errorf("Expected to stop at %s, got %s (%q)", root, m.currDir, m.news)
+	}
+}
+
+func collectGrep(root string, maxSize int) ([]grepResult, int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	msgs := make(chan tea.Msg, 1)
+	match := func(s string) bool { return strings.Contains(s, "needle") }
+	go runGrep(ctx, cancel, 0, root, false, maxSize, match, msgs)
+	var results []grepResult
+	scanned := 0
+	for msg := range msgs {
+		batch := msg.(grepBatchMsg)
+		results = append(results, batch.results...)
+		scanned = batch.scanned
+	}
+	return results, scanned
+}
+
+func TestGrep(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"bin":          "needle\x00",
+		"big":          strings.Repeat("needle\n", 20),
+		"sub/text.txt": "hay\n  needle here\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results, scanned := collectGrep(dir, 100)
+	want := grepResult{path: filepath.Join("sub", "text.txt"), line: 2, text: "needle here"}
+	if len(results) != 1 || results[0] != want {
+		t.Errorf("Expected only %v, got %v", want, results)
+	}
+	if scanned != 3 {
+		t.Errorf("Expected 3 files scanned, got %d", scanned)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "many"), []byte(strings.Repeat("needle\n", maxGrepResults+10)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := collectGrep(dir, 1<<20); len(results) != maxGrepResults {
+		t.Errorf("Expected the results to be capped at %d, got %d", maxGrepResults, len(results))
+	}
+}
+
+func TestExpandPlaceholders(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "my dir")
+	for _, name := range []string{"it's", "b c"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := NewWithConfig(Config{Dir: dir})
+	next, _ := m.update(m.readDir(dir)())
+	m = next.(Model)
+	q := func(name string) string { return shellQuote(filepath.Join(dir, name)) }
+	if got, want := m.expandPlaceholders("ls %f %s 100%% %x %"), "ls "+q("b c")+" "+q("b c")+" 100% %x %"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	m.selection[dir] = mapset.NewSet("it's", "b c")
+	if got, want := m.expandPlaceholders("cp %s %d"), "cp "+q("b c")+" "+q("it's")+" "+shellQuote(dir); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if want := `'/a/it'\''s'`; shellQuote("/a/it's") != want {
+		t.Errorf("Expected %s, got %s", want, shellQuote("/a/it's"))
+	}
+
+	empty := NewWithConfig(Config{Dir: t.TempDir()})
+	empty.openShell()
+	empty.shellInput.SetValue("echo hi")
+	if view := empty.View(); !strings.Contains(view, "echo hi") {
+		t.Errorf("Expected the shell prompt in an empty directory, got %q", view)
+	}
+}
+
+func TestTabs(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "a")
+	if err := os.Mkdir(a, 0755); err != nil {
+		t.Fatal(err)
+	}
+	m := NewWithConfig(Config{Dir: root})
+	m.newTab()
+	if len(m.tabs) != 2 || m.tabIdx != 1 || m.currDir != root {
+		t.Fatalf("Expected a second tab in %s, got %d tabs at %d in %s", root, len(m.tabs), m.tabIdx, m.currDir)
+	}
+	m.changeDir(a, "")
+	m.showHidden = true
+	m.filterInput.SetValue("x")
+	m.filterState = FilterApplied
+
+	m.switchTab(0)
+	if m.currDir != root || m.showHidden || m.filterState != Unfiltered || len(m.history) != 1 {
+		t.Errorf("Expected the first tab to be untouched, got %s (hidden %v, filter %v, history %v)", m.currDir, m.showHidden, m.filterState, m.history)
+	}
+	m.cycleTab(1)
+	if m.currDir != a || !m.showHidden || m.filterInput.Value() != "x" || len(m.history) != 2 {
+		t.Errorf("Expected the second tab's state back, got %s (hidden %v, filter %q, history %v)", m.currDir, m.showHidden, m.filterInput.Value(), m.history)
+	}
+	if bar := m.tabBar(); !strings.Contains(bar, "2 a") {
+		t.Errorf("Expected the tab bar to show the second tab in a, got %q", bar)
+	}
+
+	m.closeTab()
+	if len(m.tabs) != 1 || m.tabIdx != 0 || m.currDir != root {
+		t.Errorf("Expected to be back in the first tab, got %d tabs at %d in %s", len(m.tabs), m.tabIdx, m.currDir)
+	}
+	m.closeTab()
+	if len(m.tabs) != 1 || !strings.Contains(m.news, "last tab") {
+		t.Errorf("Expected the last tab to stay open, got %d tabs (%q)", len(m.tabs), m.news)
+	}
+}
+
+func TestPaneTransfer(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	src, dest := t.TempDir(), t.TempDir()
+	for _, p := range []string{filepath.Join(src, "a"), filepath.Join(src, "b"), filepath.Join(dest, "a")} {
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(src, filepath.Join(dest, "link")); err != nil {
+		t.Fatal(err)
+	}
+	m := NewWithConfig(Config{Dir: src, Conflict: SkipPolicy})
+	m.toggleDualPane()
+	m.pane.currDir = dest
+	m.selection[src] = mapset.NewSet("a", "b")
+	runJob(&m, m.transferToPane(CopyJob))
+	if !m.selection[src].Contains("a") || m.selection[src].Contains("b") {
+		t.Errorf("Expected only the copied b to be deselected, got %v", m.selection[src])
+	}
+	if !strings.Contains(m.news, "skipped 1") {
+		t.Errorf("Expected the skip to be reported, got %q", m.news)
+	}
+
+	m.pane.filterInput.SetValue("b")
+	m.pane.filterState = FilterApplied
+	next, cmd := m.update(m.otherPane().readDir(dest)())
+	m = next.(Model)
+	if !m.pane.linkDirs["link"] {
+		t.Errorf("Expected the pane to keep its linked directories, got %v", m.pane.linkDirs)
+	}
+	if cmd == nil {
+		t.Fatal("Expected the pane filter to run again")
+	}
+	next, _ = m.update(cmd())
+	m = next.(Model)
+	if len(m.pane.filteredFiles) != 1 || m.pane.filteredFiles[0].file.Name() != "b" {
+		t.Errorf("Expected the filtered pane to show b, got %v", m.pane.filteredFiles)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type markAction int
+
+const (
+	markNone markAction = iota
+	markSet
+	markJump
+	markRename
+)
+
+type mark struct {
+	letter  string
+	path    string
+	file    string
+	missing bool
+}
+
+func isMarkLetter(s string) bool {
+	return len(s) == 1 && ('a' <= s[0] && s[0] <= 'z' || 'A' <= s[0] && s[0] <= 'Z')
+}
+
+func loadMarks() ([]mark, error) {
+	path, err := cachePath(MarksFile)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var marks []mark
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) < 2 || !isMarkLetter(fields[0]) {
+			continue
+		}
+		mk := mark{letter: fields[0], path: unquoteField(fields[1])}
+		if len(fields) == 3 {
+			mk.file = unquoteField(fields[2])
+		}
+		marks = append(marks, mk)
+	}
+	return marks, scanner.Err()
+}
+
+func saveMarks(marks []mark) error {
+	path, err := cachePath(MarksFile)
+	if err != nil {
+		return err
+	}
+	sort.Slice(marks, func(i, j int) bool {
+		return marks[i].letter < marks[j].letter
+	})
+	var b strings.Builder
+	for _, mk := range marks {
+		fmt.Fprintf(&b, "%s\t%s\t%s\n", mk.letter, strconv.Quote(mk.path), strconv.Quote(mk.file))
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func unquoteField(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+func findMark(marks []mark, letter string) int {
+	for i, mk := range marks {
+		if mk.letter == letter {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Model) setMark(letter string) {
+	marks, err := loadMarks()
+	if err != nil {
+		m.news = fmt.Sprintf("Mark error: %s", err)
+		return
+	}
+	dir, err := filepath.Abs(m.currDir)
+	if err != nil {
+		m.news = fmt.Sprintf("Mark error: %s", err)
+		return
+	}
+	mk := mark{letter: letter, path: dir}
+	if f, ok := m.hoveredFile(); ok {
+		mk.file = f.Name()
+	}
+	if i := findMark(marks, letter); 0 <= i {
+		marks[i] = mk
+	} else {
+		marks = append(marks, mk)
+	}
+	if err := saveMarks(marks); err != nil {
+		m.news = fmt.Sprintf("Mark error: %s", err)
+		return
+	}
+	m.news = fmt.Sprintf("Marked %s as %s", dir, letter)
+}
+
+func (m *Model) jumpToMark(letter string) tea.Cmd {
+	marks, err := loadMarks()
+	if err != nil {
+		m.news = fmt.Sprintf("Mark error: %s", err)
+		return nil
+	}
+	i := findMark(marks, letter)
+	if i < 0 {
+		m.news = fmt.Sprintf("Mark %s is not set", letter)
+		return nil
+	}
+	mk := marks[i]
+	if !isDirAccessible(mk.path) {
+		m.news = fmt.Sprintf("Mark %s: %s no longer exists", letter, mk.path)
+		return nil
+	}
+	return m.changeDir(mk.path, mk.file)
+}
+
+func (m *Model) renameMark(from, to string) {
+	marks, err := loadMarks()
+	if err != nil {
+		m.news = fmt.Sprintf("Mark error: %s", err)
+		return
+	}
+	i := findMark(marks, from)
+	if i < 0 {
+		return
+	}
+	if j := findMark(marks, to); 0 <= j {
+		marks[j].letter = from
+	}
+	marks[i].letter = to
+	if err := saveMarks(marks); err != nil {
+		m.news = fmt.Sprintf("Mark error: %s", err)
+		return
+	}
+	m.news = fmt.Sprintf("Renamed mark %s to %s", from, to)
+	m.reloadMarks()
+}
+
+func (m *Model) deleteMark(letter string) {
+	marks, err := loadMarks()
+	if err != nil {
+		m.news = fmt.Sprintf("Mark error: %s", err)
+		return
+	}
+	if i := findMark(marks, letter); 0 <= i {
+		marks = append(marks[:i], marks[i+1:]...)
+	}
+	if err := saveMarks(marks); err != nil {
+		m.news = fmt.Sprintf("Mark error: %s", err)
+		return
+	}
+	m.news = fmt.Sprintf("Deleted mark %s", letter)
+	m.reloadMarks()
+}
+
+func (m *Model) openMarks() {
+	m.overlay = MarksOverlay
+	m.menu.reset()
+	m.reloadMarks()
+}
+
+func (m *Model) reloadMarks() {
+	marks, err := loadMarks()
+	if err != nil {
+		m.news = fmt.Sprintf("Mark error: %s", err)
+	}
+	for i := range marks {
+		marks[i].missing = !isDirAccessible(marks[i].path)
+	}
+	m.marks = marks
+	if len(m.marks) <= m.menu.idx {
+		m.menu.reset()
+	}
+}
+
+func (m Model) pendingMarkMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	action := m.markPending
+	m.markPending = markNone
+	letter := msg.String()
+	if !isMarkLetter(letter) {
+		m.news = ""
+		return m, nil
+	}
+	switch action {
+	case markSet:
+		m.setMark(letter)
+	case markJump:
+		m.news = ""
+		return m, m.jumpToMark(letter)
+	case markRename:
+		if m.menu.idx < len(m.marks) {
+			m.renameMark(m.marks[m.menu.idx].letter, letter)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) marksMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		hasMark := m.menu.idx < len(m.marks)
+		switch {
+		case key.Matches(msg, m.keys.CloseMenu, m.keys.ShowMarks):
+			m.overlay = NoOverlay
+		case key.Matches(msg, m.keys.Up):
+			m.menu.up()
+		case key.Matches(msg, m.keys.Down):
+			m.menu.down(len(m.marks), m.maxHeight)
+		case key.Matches(msg, m.keys.Confirm, m.keys.Right) && hasMark:
+			m.overlay = NoOverlay
+			return m, m.jumpToMark(m.marks[m.menu.idx].letter)
+		case key.Matches(msg, m.keys.MarkDelete) && hasMark:
+			m.deleteMark(m.marks[m.menu.idx].letter)
+		case key.Matches(msg, m.keys.MarkRename) && hasMark:
+			m.markPending = markRename
+			m.news = fmt.Sprintf("Rename mark %s to:", m.marks[m.menu.idx].letter)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) marksView() string {
+	if len(m.marks) == 0 {
+		return m.styles.EmptyDir.Render("No marks") + "\n"
+	}
+	items := make([]string, len(m.marks))
+	for i, mk := range m.marks {
+		items[i] = fmt.Sprintf("%s  %s", mk.letter, filepath.Join(mk.path, mk.file))
+		if mk.missing {
+			items[i] += " " + m.styles.InaccessibleDir.Render("(missing)")
+		}
+	}
+	return m.menu.view(items, m.maxHeight, m.styles)
+}
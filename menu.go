@@ -0,0 +1,64 @@
+package main
+
+type Overlay int
+
+const (
+	NoOverlay Overlay = iota
+	JobsOverlay
+	ConflictOverlay
+	TrashOverlay
+	RenameOverlay
+	SortOverlay
+	MarksOverlay
+	HistoryOverlay
+	JumpOverlay
+	FindOverlay
+	GrepOverlay
+	ShellOverlay
+	OpenWithOverlay
+)
+
+type menu struct {
+	idx int
+	min int
+}
+
+func (mn *menu) reset() {
+	mn.idx = 0
+	mn.min = 0
+}
+
+func (mn *menu) up() {
+	if 0 < mn.idx {
+		mn.idx--
+	}
+	if mn.idx < mn.min {
+		mn.min = mn.idx
+	}
+}
+
+func (mn *menu) down(length, height int) {
+	if mn.idx < length-1 {
+		mn.idx++
+	}
+	if mn.min+height <= mn.idx {
+		mn.min = mn.idx - height + 1
+	}
+}
+
+func (mn menu) view(items []string, height int, styles Styles) string {
+	s := ""
+	for i, item := range items {
+		if i < mn.min {
+			continue
+		}
+		if mn.min+height <= i {
+			break
+		}
+		if i == mn.idx {
+			item = styles.Hover.Render(item)
+		}
+		s += item + "\n"
+	}
+	return s
+}
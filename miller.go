@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type column struct {
+	id     int
+	path   string
+	files  []os.DirEntry
+	idx    int
+	min    int
+	loaded bool
+}
+
+func (c *column) scroll(height int) {
+	if c.idx < c.min {
+		c.min = c.idx
+	}
+	if c.min+height < c.idx {
+		c.min = c.idx - height
+	}
+}
+
+func (m Model) sortFor(path string) sortSettings {
+	if s, ok := m.sortSave[path]; ok {
+		return s
+	}
+	return m.defaultSort
+}
+
+func (m Model) parentPath() string {
+	abs, err := filepath.Abs(m.currDir)
+	if err != nil || abs == "/" {
+		return ""
+	}
+	return filepath.Dir(abs)
+}
+
+func (m Model) childPath() string {
+	f, ok := m.hoveredFile()
+	if !ok {
+		return ""
+	}
+	if info, ok := m.infos[f.Name()]; ok && (info.IsDir() || m.linkDirs[f.Name()]) {
+		return filepath.Join(m.currDir, f.Name())
+	}
+	return ""
+}
+
+func (m *Model) loadColumn(c *column, path string) tea.Cmd {
+	if path == c.path && c.loaded {
+		return nil
+	}
+	if path != c.path {
+		c.min = 0
+		c.files = nil
+	}
+	c.path = path
+	c.loaded = path == ""
+	if path == "" {
+		return nil
+	}
+	return m.listDir(c.id, path, m.sortFor(path))
+}
+
+func (m *Model) updateColumns() tea.Cmd {
+	if !m.millerColumns || m.overlay != NoOverlay {
+		return nil
+	}
+	return tea.Batch(
+		m.loadColumn(&m.parentCol, m.parentPath()),
+		m.loadColumn(&m.childCol, m.childPath()),
+	)
+}
+
+func (m *Model) handleColumnMsg(msg readDirMsg) {
+	var c *column
+	switch msg.id {
+	case m.parentCol.id:
+		c = &m.parentCol
+	case m.childCol.id:
+		c = &m.childCol
+	default:
+		return
+	}
+	if msg.path != c.path {
+		return
+	}
+	c.files = msg.files
+	c.loaded = true
+	c.idx = -1
+	if c == &m.parentCol {
+		base := filepath.Base(m.currDir)
+		if abs, err := filepath.Abs(m.currDir); err == nil {
+			base = filepath.Base(abs)
+		}
+		for i, f := range c.files {
+			if f.Name() == base {
+				c.idx = i
+				break
+			}
+		}
+	} else if idx, ok := m.cursorSave[c.path]; ok && idx < len(c.files) {
+		c.idx = idx
+	} else if 0 < len(c.files) {
+		c.idx = 0
+	}
+	if 0 <= c.idx {
+		c.scroll(m.maxHeight)
+	}
+}
+
+func (m Model) columnView(c column, width int) string {
+	if c.path == "" {
+		return ""
+	}
+	if c.loaded && len(c.files) == 0 {
+		return m.styles.EmptyDir.String()
+	}
+	var lines []string
+	for i := c.min; i < len(c.files) && i <= c.min+m.maxHeight; i++ {
+		f := c.files[i]
+		name := f.Name()
+		switch {
+		case i == c.idx && f.IsDir():
+			name = m.styles.DirHover.Render(name + "/")
+		case i == c.idx:
+			name = m.styles.Hover.Render(name)
+		case f.IsDir():
+			name = m.styles.Directory.Render(name + "/")
+		case f.Type()&os.ModeSymlink != 0:
+			name = m.styles.Symlink.Render(name)
+		}
+		lines = append(lines, name)
+	}
+	return fitWidth(strings.Join(lines, "\n"), width)
+}
+
+func (m Model) millerView(listing string) string {
+	if m.width == 0 {
+		return listing
+	}
+	parentWidth := m.width / 5
+	currWidth := m.width * 2 / 5
+	childWidth := m.width - parentWidth - currWidth
+	child := m.previewPane(childWidth)
+	if m.childCol.path != "" {
+		pane := m.styles.Preview.Height(m.maxHeight + 1)
+		child = lipgloss.NewStyle().MaxWidth(childWidth).Render(pane.Render(m.columnView(m.childCol, childWidth-2)))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		fitWidth(m.columnView(m.parentCol, parentWidth-1), parentWidth),
+		fitWidth(listing, currWidth),
+		child,
+	)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+var mountEscapes = strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+
+func mountPoints() []string {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if 2 <= len(fields) {
+			mounts = append(mounts, mountEscapes.Replace(fields[1]))
+		}
+	}
+	return mounts
+}
@@ -2,11 +2,9 @@ package main
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/key"
@@ -21,6 +19,16 @@ func (m Model) getFileLen() int {
 	return len(m.files)
 }
 
+func (m Model) hoveredFile() (os.DirEntry, bool) {
+	switch {
+	case m.filterState == Unfiltered && m.idx < len(m.files):
+		return m.files[m.idx], true
+	case m.filterState == FilterApplied && m.idx < len(m.filteredFiles):
+		return m.filteredFiles[m.idx].file, true
+	}
+	return nil, false
+}
+
 func getSelectedFilePaths(selection map[string]mapset.Set) []string {
 	var paths []string
 	for dir, fileSet := range selection {
@@ -32,131 +40,31 @@ func getSelectedFilePaths(selection map[string]mapset.Set) []string {
 	return paths
 }
 
-func pathExistsGiveNew(path string) (string, error) {
-	for {
-		_, err := os.Stat(path)
-		if err == nil {
-			path += "_"
-			continue
-		}
-		if os.IsNotExist(err) {
-			return path, nil
-		}
-		return path, err
-	}
-}
-
-func flattenSelected(selected map[string]mapset.Set) string {
-	s := ""
-	for dir, filesSet := range selected {
-		for file := range filesSet.Iter() {
-			s += filepath.Join(dir, file.(string)) + "\n"
-		}
-	}
-	s = s[:len(s)-1]
-	return s
-}
-
-func copyFile(src, dest string) error {
-	dest, err := pathExistsGiveNew(dest)
-	if err != nil {
-		return err
-	}
-
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer srcFile.Close()
-
-	destFile, err := os.Create(dest)
-	if err != nil {
-		return err
-	}
-	defer destFile.Close()
-
-	_, err = io.Copy(destFile, srcFile)
-	if err != nil {
-		return err
-	}
-
-	srcInfo, err := os.Stat(src)
-	if err != nil {
-		return err
-	}
-	return os.Chmod(dest, srcInfo.Mode())
-}
-
-func copyDir(src, dest string) error {
-	dest, err := pathExistsGiveNew(dest)
-	if err != nil {
-		return err
-	}
-
-	srcInfo, err := os.Stat(src)
-	if err != nil {
-		return err
-	}
-
-	err = os.MkdirAll(dest, srcInfo.Mode())
+func cachePath(name string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
 	if err != nil {
-		return err
+		return "", err
 	}
-
-	fs, err := os.ReadDir(src)
+	cacheSubDirPath := filepath.Join(cacheDir, CacheSubDir)
+	err = os.MkdirAll(cacheSubDirPath, os.ModePerm)
 	if err != nil {
-		return err
-	}
-
-	for _, f := range fs {
-		srcfilePath := filepath.Join(src, f.Name())
-		destfilePath := filepath.Join(dest, f.Name())
-		fInfo, err := f.Info()
-		if err != nil {
-			continue
-		}
-		if f.IsDir() {
-			if err = copyDir(srcfilePath, destfilePath); err != nil {
-				continue
-			}
-		} else if fInfo.Mode()&os.ModeSymlink != 0 {
-			if err = copySymlink(srcfilePath, destfilePath); err != nil {
-				continue
-			}
-		} else {
-			if err = copyFile(srcfilePath, destfilePath); err != nil {
-				continue
-			}
-		}
+		return "", err
 	}
-	return nil
+	return filepath.Join(cacheSubDirPath, name), nil
 }
 
-func copySymlink(src, dest string) error {
-	dest, err := pathExistsGiveNew(dest)
-	if err != nil {
-		return err
-	}
-
-	target, err := filepath.EvalSymlinks(src)
-	if err != nil {
-		return err
+func outputPath(path, name string) (string, error) {
+	if path != "" {
+		return path, nil
 	}
-	return os.Symlink(target, dest)
+	return cachePath(name)
 }
 
 func (m Model) quitRoutine() {
-	cacheDir, err := os.UserCacheDir()
+	fp, err := outputPath(m.cdFile, CacheFile)
 	if err != nil {
 		log.Fatal(err)
 	}
-	cacheSubDirPath := filepath.Join(cacheDir, CacheSubDir)
-	err = os.MkdirAll(cacheSubDirPath, os.ModePerm)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	fp := filepath.Join(cacheSubDirPath, CacheFile)
 	f, err := os.Create(fp)
 	if err != nil {
 		log.Fatal(err)
@@ -169,20 +77,24 @@ func (m Model) quitRoutine() {
 		log.Fatal(err)
 	}
 
-	if len(m.selection) == 0 {
-		return
+	if len(m.selection) != 0 && m.clipboardFormat.kind != OutputNone {
+		clipboard.WriteAll(m.selectionOutput(m.clipboardFormat))
 	}
-	s := strings.Replace(flattenSelected(m.selection), "\n", " ", -1)
-	clipboard.WriteAll(s)
 
-	saveEnvFp := filepath.Join(cacheSubDirPath, EnvCacheFile)
+	saveEnvFp, err := outputPath(m.selectionFile, EnvCacheFile)
+	if err != nil {
+		log.Fatal(err)
+	}
 	saveEnvF, err := os.Create(saveEnvFp)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer saveEnvF.Close()
 
-	saveEnvData := []byte(s)
+	if len(m.selection) == 0 || m.fileFormat.kind == OutputNone {
+		return
+	}
+	saveEnvData := []byte(m.selectionOutput(m.fileFormat))
 	_, err = saveEnvF.Write(saveEnvData)
 	if err != nil {
 		log.Fatal(err)
@@ -369,102 +281,104 @@ func (m *Model) cut() {
 	m.isCutting = true
 }
 
-func (m *Model) paste() {
+func (m *Model) paste() tea.Cmd {
 	if len(m.copyBuffer) == 0 {
 		m.news = "Nothing pasted"
-		return
+		return nil
 	}
-	dirEmpty := len(m.files) == 0
-	dest := m.currDir
-	anyErrors := false
-	for _, f := range m.copyBuffer {
-		fInfo, err := os.Lstat(f)
-		if err != nil {
-			anyErrors = true
-			continue
-		}
-		destFullPath := filepath.Join(dest, filepath.Base(f))
-		if fInfo.Mode()&os.ModeSymlink != 0 {
-			if err := copySymlink(f, destFullPath); err != nil {
-				anyErrors = true
-			}
-		} else if fInfo.IsDir() {
-			if err := copyDir(f, destFullPath); err != nil {
-				anyErrors = true
-			}
-		} else {
-			if err := copyFile(f, destFullPath); err != nil {
-				anyErrors = true
-			}
+	transfers := make([]transfer, len(m.copyBuffer))
+	for i, f := range m.copyBuffer {
+		transfers[i] = transfer{
+			src:  f,
+			dest: filepath.Join(m.currDir, filepath.Base(f)),
 		}
 	}
-	if dirEmpty {
-		m.idx = 0
+	kind := CopyJob
+	if m.isCutting {
+		kind = MoveJob
 	}
-	if !m.isCutting {
-		return
+	if m.filterState == FilterApplied {
+		m.filterOff()
 	}
-	if anyErrors {
-		m.news = "Error while pasting"
-		return
+	m.news = fmt.Sprintf("Started %s of %d file(s)", kind, len(transfers))
+	cmd := m.startJob(kind, transfers, journalRecord)
+	m.jobs[len(m.jobs)-1].uncut = m.isCutting
+	return cmd
+}
+
+func (m *Model) uncut(moved []string) {
+	done := make(map[string]bool, len(moved))
+	for _, f := range moved {
+		done[f] = true
 	}
+	buffer := make([]string, 0)
 	for _, f := range m.copyBuffer {
-		fInfo, err := os.Stat(f)
-		if err != nil {
-			continue
-		}
-		if fInfo.IsDir() {
-			err := os.RemoveAll(f)
-			if err != nil {
-				continue
-			}
-		} else {
-			err := os.Remove(f)
-			if err != nil {
-				continue
-			}
+		if !done[f] {
+			buffer = append(buffer, f)
 		}
 	}
-	m.isCutting = false
-	if m.filterState == FilterApplied {
-		m.filterOff()
+	m.copyBuffer = buffer
+	if len(m.copyBuffer) == 0 {
+		m.isCutting = false
 	}
 }
 
+func (m *Model) changeDir(dir, lastFile string) tea.Cmd {
+	m.pushHistory(dir)
+	return m.visitDir(dir, lastFile)
+}
+
+func (m *Model) visitDir(dir, lastFile string) tea.Cmd {
+	m.cursorSave[m.currDir] = m.idx
+	if dir != m.currDir {
+		m.prevDir = m.currDir
+	}
+	m.recordVisit(dir)
+	m.currDir = dir
+	m.lastFile = lastFile
+	if val, ok := m.cursorSave[m.currDir]; ok {
+		m.idx = val
+	} else {
+		m.idx = 0
+	}
+	if s, ok := m.sortSave[m.currDir]; ok {
+		m.sort = s
+	} else {
+		m.sort = m.defaultSort
+	}
+	m.min = 0
+	m.max = m.maxHeight
+	m.filterOff()
+	return tea.Batch(m.readDir(m.currDir), recordFrecency(m.currDir))
+}
+
 func (m Model) left() (tea.Model, tea.Cmd) {
 	if m.currDir == "/" {
 		return m, nil
 	}
-	m.lastFile = filepath.Base(m.currDir)
-	m.cursorSave[m.currDir] = m.idx
 	newDir, err := filepath.Abs(m.currDir)
 	if err != nil {
 		log.Fatal(err)
 	}
-	newDir = filepath.Dir(newDir)
-	m.currDir = newDir
-	m.min = 0
-	m.max = m.maxHeight
-	m.filterOff()
-	return m, m.readDir(m.currDir)
+	return m, m.changeDir(filepath.Dir(newDir), filepath.Base(m.currDir))
 }
 
 func (m Model) right() (tea.Model, tea.Cmd) {
-	var f os.DirEntry
-	if m.filterState == Unfiltered {
-		f = m.files[m.idx]
-	} else if m.filterState == FilterApplied {
-		f = m.filteredFiles[m.idx].file
+	f, ok := m.hoveredFile()
+	if !ok {
+		return m, nil
 	}
-	info, err := f.Info()
+	info, err := m.fileInfo(f)
 	if err != nil {
 		return m, nil
 	}
 	isSymlink := info.Mode()&os.ModeSymlink != 0
-	if len(m.files) == 0 || (!f.IsDir() && !isSymlink) {
+	if path, ok := m.hoveredRegularFile(); ok {
+		return m, m.openFile(path)
+	}
+	if !f.IsDir() && !isSymlink {
 		return m, nil
 	}
-	oldDir := m.currDir
 	newPath := filepath.Join(m.currDir, f.Name())
 	if !isDirAccessible(newPath) {
 		return m, nil
@@ -481,54 +395,16 @@ func (m Model) right() (tea.Model, tea.Cmd) {
 		if !targetInfo.IsDir() {
 			return m, nil
 		}
-		m.currDir = target
-	} else {
-		m.currDir = newPath
+		newPath = target
 	}
-	m.cursorSave[oldDir] = m.idx
-	m.lastFile = ""
-	if val, ok := m.cursorSave[m.currDir]; ok {
-		m.idx = val
-	} else {
-		m.idx = 0
-	}
-	m.min = 0
-	m.max = m.maxHeight
-	m.filterOff()
-	return m, m.readDir(m.currDir)
+	return m, m.changeDir(newPath, "")
 }
 
 func (m Model) toggleDots() (tea.Model, tea.Cmd) {
-	var hiddenCount int
-	if m.showHidden {
-		for _, f := range m.files {
-			if f.Name()[0] != '.' {
-				break
-			}
-			hiddenCount++
-		}
-	} else {
-		dirEntries, err := os.ReadDir(m.currDir)
-		if err != nil {
-			return m, nil
-		}
-		for _, f := range dirEntries {
-			if f.Name()[0] != '.' {
-				break
-			}
-			hiddenCount++
-		}
+	if f, ok := m.hoveredFile(); ok {
+		m.lastFile = f.Name()
 	}
 	m.showHidden = !m.showHidden
-	if m.showHidden {
-		m.idx += hiddenCount
-	} else {
-		if m.idx < hiddenCount {
-			m.idx = 0
-		} else {
-			m.idx -= hiddenCount
-		}
-	}
 	return m, m.readDir(m.currDir)
 }
 
@@ -537,18 +413,7 @@ func (m Model) goHome() (tea.Model, tea.Cmd) {
 	if err != nil {
 		return m, nil
 	}
-	m.cursorSave[m.currDir] = m.idx
-	m.currDir = homeDir
-	m.lastFile = ""
-	if val, ok := m.cursorSave[m.currDir]; ok {
-		m.idx = val
-	} else {
-		m.idx = 0
-	}
-	m.min = 0
-	m.max = m.maxHeight
-	m.filterOff()
-	return m, m.readDir(m.currDir)
+	return m, m.changeDir(homeDir, "")
 }
 
 func (m Model) normalMode(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -560,6 +425,7 @@ func (m Model) normalMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.Quit):
 			m.news = oldNews
 			m.quitRoutine()
+			m.quitting = true
 			return m, tea.Quit
 		case key.Matches(msg, m.keys.Up):
 			m.up()
@@ -590,8 +456,79 @@ func (m Model) normalMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.Cut):
 			m.cut()
 		case key.Matches(msg, m.keys.Paste):
-			m.paste()
-			return m, m.readDir(m.currDir)
+			return m, m.paste()
+		case key.Matches(msg, m.keys.Undo):
+			return m, m.undo()
+		case key.Matches(msg, m.keys.Redo):
+			return m, m.redo()
+		case key.Matches(msg, m.keys.TogglePreview):
+			m.showPreview = !m.showPreview
+			m.previewPath = ""
+		case key.Matches(msg, m.keys.Find):
+			return m, m.openFind()
+		case key.Matches(msg, m.keys.DualPane):
+			return m, m.toggleDualPane()
+		case key.Matches(msg, m.keys.SwitchPane):
+			return m, m.switchPane()
+		case key.Matches(msg, m.keys.CopyToPane):
+			return m, m.transferToPane(CopyJob)
+		case key.Matches(msg, m.keys.MoveToPane):
+			return m, m.transferToPane(MoveJob)
+		case key.Matches(msg, m.keys.NewTab):
+			return m, m.newTab()
+		case key.Matches(msg, m.keys.CloseTab):
+			return m, m.closeTab()
+		case key.Matches(msg, m.keys.NextTab):
+			return m, m.cycleTab(1)
+		case key.Matches(msg, m.keys.PrevTab):
+			return m, m.cycleTab(-1)
+		case key.Matches(msg, m.keys.JumpTab):
+			return m, m.jumpToTab(msg.String())
+		case key.Matches(msg, m.keys.OpenWith):
+			m.openWith()
+			return m, nil
+		case key.Matches(msg, m.keys.Shell):
+			return m, m.openShell()
+		case key.Matches(msg, m.keys.Grep):
+			m.openGrep()
+		case key.Matches(msg, m.keys.JumpPrompt):
+			return m, m.openJump()
+		case key.Matches(msg, m.keys.HistoryBack):
+			return m, m.historyBack()
+		case key.Matches(msg, m.keys.HistoryForward):
+			return m, m.historyForward()
+		case key.Matches(msg, m.keys.PrevDir):
+			return m, m.toggleDir()
+		case key.Matches(msg, m.keys.ShowHistory):
+			m.openHistory()
+		case key.Matches(msg, m.keys.SetMark):
+			m.markPending = markSet
+			m.news = "Mark:"
+		case key.Matches(msg, m.keys.JumpToMark):
+			m.markPending = markJump
+			m.news = "Jump to mark:"
+		case key.Matches(msg, m.keys.ShowMarks):
+			m.openMarks()
+		case key.Matches(msg, m.keys.MillerColumns):
+			m.millerColumns = !m.millerColumns
+			m.previewPath = ""
+			m.parentCol.loaded = false
+			m.childCol.loaded = false
+		case key.Matches(msg, m.keys.LongListing):
+			m.longListing = !m.longListing
+		case key.Matches(msg, m.keys.Sort):
+			m.overlay = SortOverlay
+			m.menu.reset()
+			m.menu.idx = int(m.sort.mode)
+		case key.Matches(msg, m.keys.BulkRename):
+			return m, m.bulkRename()
+		case key.Matches(msg, m.keys.Trash):
+			return m, m.trashSelection()
+		case key.Matches(msg, m.keys.ShowTrash):
+			m.openTrash()
+		case key.Matches(msg, m.keys.ShowJobs):
+			m.overlay = JobsOverlay
+			m.menu.reset()
 		case key.Matches(msg, m.keys.Left):
 			return m.left()
 		case key.Matches(msg, m.keys.Right):
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type matchKind int
+
+const (
+	matchExt matchKind = iota
+	matchGlob
+	matchMIME
+)
+
+type opener struct {
+	kind    matchKind
+	pattern string
+	gui     bool
+	command string
+}
+
+type openMsg struct {
+	err error
+}
+
+func parseOpener(args []string) (opener, error) {
+	if len(args) < 2 {
+		return opener{}, errors.New(`usage: open <ext:|glob:|mime:pattern> [term|gui] <command>`)
+	}
+	var o opener
+	kind, pattern, ok := strings.Cut(args[0], ":")
+	if !ok || pattern == "" {
+		return o, fmt.Errorf("invalid matcher %q", args[0])
+	}
+	switch kind {
+	case "ext":
+		o.kind = matchExt
+		pattern = strings.ToLower(strings.TrimPrefix(pattern, "."))
+	case "glob":
+		o.kind = matchGlob
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return o, fmt.Errorf("invalid glob %q", pattern)
+		}
+	case "mime":
+		o.kind = matchMIME
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return o, fmt.Errorf("invalid mime pattern %q", pattern)
+		}
+	default:
+		return o, fmt.Errorf("unknown matcher %q", kind)
+	}
+	o.pattern = pattern
+	args = args[1:]
+	switch args[0] {
+	case "term", "gui":
+		o.gui = args[0] == "gui"
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return o, errors.New("missing command")
+	}
+	o.command = strings.Join(args, " ")
+	return o, nil
+}
+
+func (o opener) String() string {
+	mode := "term"
+	if o.gui {
+		mode = "gui"
+	}
+	if o.command == "" {
+		return "$EDITOR (" + mode + ")"
+	}
+	return fmt.Sprintf("%s (%s)", o.command, mode)
+}
+
+func (o opener) matches(path, mimeType string) bool {
+	switch o.kind {
+	case matchExt:
+		name := strings.ToLower(filepath.Base(path))
+		return strings.HasSuffix(name, "."+o.pattern)
+	case matchGlob:
+		ok, _ := filepath.Match(o.pattern, filepath.Base(path))
+		return ok
+	}
+	ok, _ := filepath.Match(o.pattern, mimeType)
+	return ok
+}
+
+func detectMIME(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+func matchingOpeners(openers []opener, path string) []opener {
+	mimeType := detectMIME(path)
+	var matches []opener
+	for _, o := range openers {
+		if o.matches(path, mimeType) {
+			matches = append(matches, o)
+		}
+	}
+	if strings.HasPrefix(mimeType, "text/") {
+		matches = append(matches, opener{})
+	}
+	return matches
+}
+
+func (m Model) hoveredRegularFile() (string, bool) {
+	f, ok := m.hoveredFile()
+	if !ok {
+		return "", false
+	}
+	path := filepath.Join(m.currDir, f.Name())
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return path, true
+}
+
+func (m Model) runOpener(o opener, path string) tea.Cmd {
+	if o.command == "" {
+		return tea.ExecProcess(editorCommand(path), func(err error) tea.Msg {
+			return editorMsg{err: err}
+		})
+	}
+	cmdline := o.command
+	if strings.Contains(cmdline, "%f") {
+		cmdline = strings.ReplaceAll(cmdline, "%f", shellQuote(path))
+	} else {
+		cmdline += " " + shellQuote(path)
+	}
+	cmd := shellCommand(cmdline, false)
+	cmd.Dir = m.currDir
+	if !o.gui {
+		return tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return openMsg{err: err}
+		})
+	}
+	return func() tea.Msg {
+		detach(cmd)
+		if err := cmd.Start(); err != nil {
+			return openMsg{err: err}
+		}
+		go cmd.Wait()
+		return nil
+	}
+}
+
+func (m *Model) openFile(path string) tea.Cmd {
+	openers := matchingOpeners(m.openers, path)
+	if len(openers) == 0 {
+		m.news = "No opener for " + filepath.Base(path)
+		return nil
+	}
+	return m.runOpener(openers[0], path)
+}
+
+func (m *Model) openWith() {
+	path, ok := m.hoveredRegularFile()
+	if !ok {
+		return
+	}
+	m.openWithPath = path
+	m.openWithMatches = matchingOpeners(m.openers, path)
+	if len(m.openWithMatches) == 0 {
+		m.news = "No opener for " + filepath.Base(path)
+		return
+	}
+	m.overlay = OpenWithOverlay
+	m.menu.reset()
+}
+
+func (m Model) openWithMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.CloseMenu, m.keys.OpenWith):
+			m.overlay = NoOverlay
+		case key.Matches(msg, m.keys.Up):
+			m.menu.up()
+		case key.Matches(msg, m.keys.Down):
+			m.menu.down(len(m.openWithMatches), m.maxHeight)
+		case key.Matches(msg, m.keys.Confirm, m.keys.Right) && m.menu.idx < len(m.openWithMatches):
+			m.overlay = NoOverlay
+			return m, m.runOpener(m.openWithMatches[m.menu.idx], m.openWithPath)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) openWithView() string {
+	items := make([]string, len(m.openWithMatches))
+	for i, o := range m.openWithMatches {
+		items[i] = o.String()
+	}
+	return m.menu.view(items, m.maxHeight, m.styles)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type OutputKind int
+
+const (
+	OutputNone OutputKind = iota
+	OutputSpace
+	OutputNewline
+	OutputNul
+	OutputShell
+	OutputJSON
+)
+
+var outputKindNames = []string{"none", "space", "newline", "nul", "shell", "json"}
+
+type OutputFormat struct {
+	kind     OutputKind
+	relative bool
+}
+
+func parseOutputFormat(s string) (OutputFormat, error) {
+	name, modifier, hasModifier := strings.Cut(s, ",")
+	var f OutputFormat
+	if hasModifier {
+		if modifier != "relative" {
+			return f, fmt.Errorf("unknown modifier %q, expected relative", modifier)
+		}
+		f.relative = true
+	}
+	for i, n := range outputKindNames {
+		if n == name {
+			f.kind = OutputKind(i)
+			return f, nil
+		}
+	}
+	return f, fmt.Errorf("expected one of %s, optionally followed by ,relative, got %q", strings.Join(outputKindNames, ", "), s)
+}
+
+func (f OutputFormat) format(paths []string, dir string) string {
+	paths = append([]string(nil), paths...)
+	sort.Strings(paths)
+	if f.relative {
+		for i, p := range paths {
+			if rel, err := filepath.Rel(dir, p); err == nil {
+				paths[i] = rel
+			}
+		}
+	}
+	switch f.kind {
+	case OutputSpace:
+		return strings.Join(paths, " ")
+	case OutputNewline:
+		return strings.Join(paths, "\n") + "\n"
+	case OutputNul:
+		return strings.Join(paths, "\x00") + "\x00"
+	case OutputShell:
+		quoted := make([]string, len(paths))
+		for i, p := range paths {
+			quoted[i] = shellQuote(p)
+		}
+		return strings.Join(quoted, " ")
+	case OutputJSON:
+		// Bytes that are not valid UTF-8 become U+FFFD, as documented.
+		data, _ := json.Marshal(paths)
+		return string(data)
+	}
+	return ""
+}
+
+func (m Model) selectionOutput(f OutputFormat) string {
+	return f.format(getSelectedFilePaths(m.selection), m.currDir)
+}
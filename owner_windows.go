@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os"
+
+func fileOwner(info os.FileInfo) (string, string) {
+	return "-", "-"
+}
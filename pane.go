@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type paneFilterMsg struct {
+	id  int
+	msg tea.Msg
+}
+
+type paneState struct {
+	pane  tab
+	right bool
+}
+
+func (m Model) otherPane() Model {
+	other := m
+	other.tab = m.pane
+	return other
+}
+
+func (m *Model) reloadPane() tea.Cmd {
+	if !m.dualPane {
+		return nil
+	}
+	other := m.otherPane()
+	if f, ok := other.hoveredFile(); ok && m.pane.filterState == Unfiltered {
+		m.pane.lastFile = f.Name()
+	}
+	return other.readDir(m.pane.currDir)
+}
+
+func (m *Model) handlePaneMsg(msg readDirMsg) tea.Cmd {
+	m.pane.files = msg.files
+	m.pane.infos = msg.infos
+	m.pane.linkDirs = msg.linkDirs
+	m.pane.max = m.pane.min + m.maxHeight
+	m.pane.refreshFiles()
+	if m.pane.filterState == FilterApplied {
+		return m.filterPane()
+	}
+	return nil
+}
+
+func (m Model) filterPane() tea.Cmd {
+	id, filter := m.pane.id, filterFiles(m.otherPane())
+	return func() tea.Msg {
+		return paneFilterMsg{id: id, msg: filter()}
+	}
+}
+
+func (m *Model) handlePaneFilterMsg(msg paneFilterMsg) {
+	if msg.id != m.pane.id {
+		return
+	}
+	switch msg := msg.msg.(type) {
+	case FilterMatchesMsg:
+		m.pane.filteredFiles = filteredFiles(msg)
+		m.pane.filterErr = nil
+	case filterErrorMsg:
+		m.pane.filterErr = msg.err
+	}
+}
+
+func (m *Model) toggleDualPane() tea.Cmd {
+	m.dualPane = !m.dualPane
+	if !m.dualPane {
+		return nil
+	}
+	m.ensurePane()
+	return m.reloadPane()
+}
+
+func (m *Model) ensurePane() {
+	if m.pane.id == 0 {
+		m.pane = newTab(m.currDir, m.sort, m.filterKind, m.showHidden)
+	}
+}
+
+func (m *Model) switchPane() tea.Cmd {
+	if !m.dualPane {
+		return nil
+	}
+	if f, ok := m.hoveredFile(); ok && m.filterState == Unfiltered {
+		m.lastFile = f.Name()
+	}
+	m.tab, m.pane = m.pane, m.tab
+	m.paneRight = !m.paneRight
+	m.max = m.min + m.maxHeight
+	m.previewPath = ""
+	return tea.Batch(m.readDir(m.currDir), m.reloadPane())
+}
+
+func (m *Model) transferToPane(kind JobKind) tea.Cmd {
+	if !m.dualPane {
+		m.news = "Dual-pane mode is off"
+		return nil
+	}
+	paths := m.selectedOrHovered()
+	if len(paths) == 0 {
+		m.news = "Nothing to " + kind.String()
+		return nil
+	}
+	transfers := make([]transfer, len(paths))
+	for i, p := range paths {
+		transfers[i] = transfer{src: p, dest: filepath.Join(m.pane.currDir, filepath.Base(p))}
+	}
+	m.news = fmt.Sprintf("Started %s of %d file(s) to %s", kind, len(transfers), m.pane.currDir)
+	cmd := m.startJob(kind, transfers, journalRecord)
+	m.jobs[len(m.jobs)-1].deselect = true
+	return cmd
+}
+
+func (m Model) dualView(hovered, filterBar, files string) string {
+	width := m.width / 2
+	if width == 0 {
+		width = 40
+	}
+	other := m.otherPane()
+	otherFiles, _ := other.listing()
+	otherFiles = strings.TrimSuffix(otherFiles, "\n")
+	if len(m.files) == 0 {
+		files = m.styles.EmptyDir.String()
+	}
+	if len(other.files) == 0 {
+		otherFiles = m.styles.EmptyDir.String()
+	}
+	path := m.currDir
+	if path != "/" {
+		path += "/"
+	}
+	header := m.styles.Path.Render(path) + hovered
+	otherHeader := m.styles.Details.Render(m.pane.currDir)
+	otherBar := "\n\n"
+	if m.pane.filterState == Filtering || m.pane.filterState == FilterApplied {
+		otherBar = "\n" + m.styles.Details.Render(m.pane.filterInput.Prompt+m.pane.filterInput.Value()) + "\n"
+	}
+	left := fitWidth(header+filterBar+files, width)
+	right := m.styles.Pane.Render(fitWidth(otherHeader+otherBar+otherFiles, max(m.width-width-2, 1)))
+	if m.paneRight {
+		left = fitWidth(otherHeader+otherBar+otherFiles, width)
+		right = m.styles.Pane.Render(fitWidth(header+filterBar+files, max(m.width-width-2, 1)))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+}
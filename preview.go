@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const previewBytes = 64 * 1024
+
+type previewMsg struct {
+	path  string
+	lines []string
+	err   error
+}
+
+func (m Model) hoveredPath() string {
+	f, ok := m.hoveredFile()
+	if !ok {
+		return ""
+	}
+	return filepath.Join(m.currDir, f.Name())
+}
+
+func (m Model) readPreview(path string) tea.Cmd {
+	height, showHidden := m.maxHeight, m.showHidden
+	return func() tea.Msg {
+		lines, err := previewLines(path, height, showHidden)
+		return previewMsg{path: path, lines: lines, err: err}
+	}
+}
+
+func previewLines(path string, height int, showHidden bool) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return previewDir(path, height, showHidden)
+	}
+	if !info.Mode().IsRegular() {
+		return []string{"Not a regular file (" + info.Mode().String() + ")"}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, previewBytes))
+	if err != nil {
+		return nil, err
+	}
+	if isBinary(data) {
+		if n := height * 16; n < len(data) {
+			data = data[:n]
+		}
+		return strings.Split(strings.TrimSuffix(hex.Dump(data), "\n"), "\n"), nil
+	}
+	lines := strings.Split(string(data), "\n")
+	if height < len(lines) {
+		lines = lines[:height]
+	}
+	for i, line := range lines {
+		lines[i] = sanitizeLine(line)
+	}
+	return lines, nil
+}
+
+func previewDir(path string, height int, showHidden bool) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, f := range entries {
+		if height <= len(lines) {
+			break
+		}
+		if !showHidden {
+			if hidden, err := isHidden(filepath.Join(path, f.Name())); err == nil && hidden {
+				continue
+			}
+		}
+		name := f.Name()
+		if f.IsDir() {
+			name += "/"
+		}
+		lines = append(lines, name)
+	}
+	return lines, nil
+}
+
+func isBinary(data []byte) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return true
+	}
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 && utf8.UTFMax <= len(data) {
+			return true
+		}
+		data = data[size:]
+	}
+	return false
+}
+
+func sanitizeLine(s string) string {
+	s = strings.ReplaceAll(strings.TrimSuffix(s, "\r"), "\t", "    ")
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func (m *Model) updatePreview() tea.Cmd {
+	if !m.showPreview && !m.millerColumns || m.overlay != NoOverlay {
+		return nil
+	}
+	path := m.hoveredPath()
+	if path == m.previewPath {
+		return nil
+	}
+	m.previewPath = path
+	m.previewLines = nil
+	m.previewErr = nil
+	if path == "" {
+		return nil
+	}
+	return m.readPreview(path)
+}
+
+func (m Model) previewView(listing string) string {
+	if m.width == 0 {
+		return listing
+	}
+	width := m.width / 2
+	return lipgloss.JoinHorizontal(lipgloss.Top, fitWidth(listing, width), m.previewPane(m.width-width))
+}
+
+func (m Model) previewPane(width int) string {
+	var text string
+	switch {
+	case m.previewErr != nil:
+		text = m.styles.InaccessibleDir.Render(m.previewErr.Error())
+	case len(m.previewLines) == 0 && m.previewPath != "":
+		text = m.styles.EmptyDir.String()
+	default:
+		text = strings.Join(m.previewLines, "\n")
+	}
+	pane := m.styles.Preview.Height(m.maxHeight + 1)
+	return lipgloss.NewStyle().MaxWidth(width).Render(pane.Render(text))
+}
+
+func fitWidth(s string, width int) string {
+	return lipgloss.NewStyle().Width(width).Render(lipgloss.NewStyle().MaxWidth(width).Render(s))
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	mapset "github.com/deckarep/golang-set"
+)
+
+const renameOp = "rename"
+
+type bulkRenameMsg struct {
+	tmp       string
+	dir       string
+	originals []string
+	err       error
+}
+
+type renamePlan struct {
+	renames []transfer
+	cycles  map[int]bool
+}
+
+func editorCommand(args ...string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	fields := strings.Fields(editor)
+	return exec.Command(fields[0], append(fields[1:], args...)...)
+}
+
+func (m *Model) bulkRename() tea.Cmd {
+	var originals []string
+	if 0 < len(m.selection) {
+		originals = getSelectedFilePaths(m.selection)
+		sort.Strings(originals)
+	} else {
+		for _, f := range m.files {
+			originals = append(originals, f.Name())
+		}
+	}
+	if len(originals) == 0 {
+		m.news = "Nothing to rename"
+		return nil
+	}
+	for _, name := range originals {
+		if strings.Contains(name, "\n") {
+			m.news = fmt.Sprintf("Cannot rename %q: name contains a newline", name)
+			return nil
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "nav-rename-*.txt")
+	if err != nil {
+		m.news = fmt.Sprintf("Rename error: %s", err)
+		return nil
+	}
+	_, err = tmp.WriteString(strings.Join(originals, "\n") + "\n")
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		m.news = fmt.Sprintf("Rename error: %s", err)
+		return nil
+	}
+
+	dir := m.currDir
+	return tea.ExecProcess(editorCommand(tmp.Name()), func(err error) tea.Msg {
+		return bulkRenameMsg{tmp: tmp.Name(), dir: dir, originals: originals, err: err}
+	})
+}
+
+func readEditedNames(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+func resolveName(dir, name string) string {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	return filepath.Join(dir, name)
+}
+
+func planRenames(dir string, originals, edited []string) (renamePlan, error) {
+	var plan renamePlan
+	if len(originals) != len(edited) {
+		return plan, fmt.Errorf("expected %d lines, got %d", len(originals), len(edited))
+	}
+	sources := make(map[string]bool)
+	for _, name := range originals {
+		sources[resolveName(dir, name)] = true
+	}
+	targets := make(map[string]string)
+	var errs []error
+	for i, name := range edited {
+		src := resolveName(dir, originals[i])
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, fmt.Errorf("line %d: empty name for %s", i+1, originals[i]))
+			continue
+		}
+		dest := resolveName(filepath.Dir(src), name)
+		if other, ok := targets[dest]; ok {
+			errs = append(errs, fmt.Errorf("line %d: %s is also the new name of %s", i+1, name, other))
+			continue
+		}
+		targets[dest] = originals[i]
+		if dest == src {
+			continue
+		}
+		if _, err := os.Lstat(dest); err == nil && !sources[dest] {
+			errs = append(errs, fmt.Errorf("line %d: %s already exists", i+1, name))
+			continue
+		}
+		if _, err := os.Stat(filepath.Dir(dest)); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", i+1, err))
+			continue
+		}
+		plan.renames = append(plan.renames, transfer{src: src, dest: dest})
+	}
+	if err := errors.Join(errs...); err != nil {
+		return plan, err
+	}
+	plan.cycles = findCycles(plan.renames)
+	return plan, nil
+}
+
+func findCycles(renames []transfer) map[int]bool {
+	bySrc := make(map[string]int)
+	for i, r := range renames {
+		bySrc[r.src] = i
+	}
+	cycles := make(map[int]bool)
+	for start := range renames {
+		i, steps := start, 0
+		for steps <= len(renames) {
+			next, ok := bySrc[renames[i].dest]
+			if !ok {
+				break
+			}
+			i = next
+			steps++
+			if i == start {
+				cycles[start] = true
+				break
+			}
+		}
+	}
+	return cycles
+}
+
+func applyRenames(renames []transfer) error {
+	var done []transfer
+	rename := func(src, dest string) error {
+		if err := renameNoReplace(src, dest); err != nil {
+			return rollbackRenames(done, err)
+		}
+		done = append(done, transfer{src: src, dest: dest})
+		return nil
+	}
+	pending := append([]transfer(nil), renames...)
+	for 0 < len(pending) {
+		occupied := make(map[string]bool)
+		for _, r := range pending {
+			occupied[r.src] = true
+		}
+		var rest []transfer
+		for _, r := range pending {
+			if occupied[r.dest] {
+				rest = append(rest, r)
+				continue
+			}
+			if err := rename(r.src, r.dest); err != nil {
+				return err
+			}
+			delete(occupied, r.src)
+		}
+		if len(rest) == len(pending) {
+			tmp, err := tempSibling(rest[0].src)
+			if err != nil {
+				return rollbackRenames(done, err)
+			}
+			if err := rename(rest[0].src, tmp); err != nil {
+				return err
+			}
+			rest[0].src = tmp
+		}
+		pending = rest
+	}
+	return nil
+}
+
+func rollbackRenames(done []transfer, cause error) error {
+	errs := []error{cause}
+	for i := len(done) - 1; 0 <= i; i-- {
+		if err := renameNoReplace(done[i].dest, done[i].src); err != nil {
+			errs = append(errs, fmt.Errorf("could not restore %s, it is still named %s", done[i].src, done[i].dest))
+		}
+	}
+	if len(errs) == 1 {
+		errs = append(errs, errors.New("all renames were rolled back"))
+	}
+	return errors.Join(errs...)
+}
+
+// checkedRename refuses to replace dest, which may have been created since the
+// renames were planned, unless it is src under another case.
+func checkedRename(src, dest string) error {
+	if destInfo, err := os.Lstat(dest); err == nil {
+		srcInfo, err := os.Lstat(src)
+		if err != nil || !os.SameFile(srcInfo, destInfo) {
+			return &os.LinkError{Op: "rename", Old: src, New: dest, Err: fs.ErrExist}
+		}
+	}
+	return os.Rename(src, dest)
+}
+
+func tempSibling(path string) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), ".nav-rename-*")
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+	f.Close()
+	return name, os.Remove(name)
+}
+
+func (m *Model) handleBulkRenameMsg(msg bulkRenameMsg) tea.Cmd {
+	defer os.Remove(msg.tmp)
+	if msg.err != nil {
+		m.news = fmt.Sprintf("Editor error: %s", msg.err)
+		return nil
+	}
+	edited, err := readEditedNames(msg.tmp)
+	if err != nil {
+		m.news = fmt.Sprintf("Rename error: %s", err)
+		return nil
+	}
+	plan, err := planRenames(msg.dir, msg.originals, edited)
+	if err != nil {
+		m.news = fmt.Sprintf("Rename aborted: %s", strings.ReplaceAll(err.Error(), "\n", "; "))
+		return nil
+	}
+	if len(plan.renames) == 0 {
+		m.news = "No names changed"
+		return nil
+	}
+	m.renamePlan = plan
+	m.overlay = RenameOverlay
+	m.menu.reset()
+	return nil
+}
+
+func (m *Model) confirmRenames() tea.Cmd {
+	renames := m.renamePlan.renames
+	m.renamePlan = renamePlan{}
+	m.overlay = NoOverlay
+	if err := applyRenames(renames); err != nil {
+		m.news = fmt.Sprintf("Rename failed: %s", strings.ReplaceAll(err.Error(), "\n", "; "))
+		return m.readDir(m.currDir)
+	}
+	items := make([]journalItem, len(renames))
+	for i, r := range renames {
+		items[i] = journalItem{Src: r.src, Dest: r.dest, Fingerprint: takeFingerprint(r.dest)}
+		dir, name := filepath.Split(r.src)
+		if fileSet, ok := m.selection[filepath.Clean(dir)]; ok && fileSet.Contains(name) {
+			fileSet.Remove(name)
+			newDir, newName := filepath.Split(r.dest)
+			newDir = filepath.Clean(newDir)
+			if _, ok := m.selection[newDir]; !ok {
+				m.selection[newDir] = mapset.NewSet()
+			}
+			m.selection[newDir].Add(newName)
+			if fileSet.Cardinality() == 0 {
+				delete(m.selection, filepath.Clean(dir))
+			}
+		}
+	}
+	m.news = fmt.Sprintf("Renamed %d file(s)", len(renames))
+	if err := recordOperation(renameOp, items, false); err != nil {
+		m.news = fmt.Sprintf("Journal error: %s", err)
+	}
+	return m.readDir(m.currDir)
+}
+
+func (m Model) renameMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.CloseMenu):
+			m.renamePlan = renamePlan{}
+			m.overlay = NoOverlay
+			m.news = "Rename cancelled"
+		case key.Matches(msg, m.keys.Up):
+			m.menu.up()
+		case key.Matches(msg, m.keys.Down):
+			m.menu.down(len(m.renamePlan.renames), m.maxHeight)
+		case key.Matches(msg, m.keys.Confirm):
+			return m, m.confirmRenames()
+		}
+	}
+	return m, nil
+}
+
+func (m Model) renameView() string {
+	items := make([]string, len(m.renamePlan.renames))
+	for i, r := range m.renamePlan.renames {
+		src, dest := r.src, r.dest
+		if rel, err := filepath.Rel(m.currDir, src); err == nil && !strings.HasPrefix(rel, "..") {
+			src = rel
+		}
+		if rel, err := filepath.Rel(m.currDir, dest); err == nil && !strings.HasPrefix(rel, "..") {
+			dest = rel
+		}
+		items[i] = fmt.Sprintf("%s -> %s", src, dest)
+		if m.renamePlan.cycles[i] {
+			items[i] += " (cycle)"
+		}
+	}
+	h := m.keys.Confirm.Help()
+	c := m.keys.CloseMenu.Help()
+	header := fmt.Sprintf("Rename %d file(s)? %s %s, %s cancel\n\n",
+		len(items), m.styles.PathEnd.Render(h.Key), h.Desc, m.styles.PathEnd.Render(c.Key))
+	return header + m.menu.view(items, m.maxHeight-2, m.styles)
+}
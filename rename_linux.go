@@ -0,0 +1,19 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func renameNoReplace(src, dest string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, src, unix.AT_FDCWD, dest, unix.RENAME_NOREPLACE)
+	if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOSYS) {
+		return checkedRename(src, dest)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: src, New: dest, Err: err}
+	}
+	return nil
+}
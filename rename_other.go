@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+func renameNoReplace(src, dest string) error {
+	return checkedRename(src, dest)
+}
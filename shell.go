@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type shellDoneMsg struct {
+	cmdline string
+	err     error
+}
+
+func newShellInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "!"
+	input.Placeholder = "%f hovered file, %s selection, %d directory"
+	return input
+}
+
+func (m Model) expandPlaceholders(cmdline string) string {
+	var b strings.Builder
+	for i := 0; i < len(cmdline); i++ {
+		if cmdline[i] != '%' || i+1 == len(cmdline) {
+			b.WriteByte(cmdline[i])
+			continue
+		}
+		i++
+		switch cmdline[i] {
+		case 'f':
+			if f, ok := m.hoveredFile(); ok {
+				b.WriteString(shellQuote(filepath.Join(m.currDir, f.Name())))
+			}
+		case 's':
+			paths := m.selectedOrHovered()
+			sort.Strings(paths)
+			for j, p := range paths {
+				if 0 < j {
+					b.WriteByte(' ')
+				}
+				b.WriteString(shellQuote(p))
+			}
+		case 'd':
+			b.WriteString(shellQuote(m.currDir))
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(cmdline[i])
+		}
+	}
+	return b.String()
+}
+
+func (m *Model) openShell() tea.Cmd {
+	m.shellInput.Reset()
+	m.overlay = ShellOverlay
+	return m.shellInput.Focus()
+}
+
+func (m *Model) runShell(cmdline string) tea.Cmd {
+	cmd := shellCommand(m.expandPlaceholders(cmdline), m.shellPause)
+	cmd.Dir = m.currDir
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return shellDoneMsg{cmdline: cmdline, err: err}
+	})
+}
+
+func (m *Model) handleShellDone(msg shellDoneMsg) tea.Cmd {
+	m.news = fmt.Sprintf("Ran %s", msg.cmdline)
+	if msg.err != nil {
+		m.news = fmt.Sprintf("%s: %s", msg.cmdline, msg.err)
+	}
+	if f, ok := m.hoveredFile(); ok && m.filterState == Unfiltered {
+		m.lastFile = f.Name()
+	}
+	return m.readDir(m.currDir)
+}
+
+func (m Model) shellMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.keys.FilterOff):
+			m.overlay = NoOverlay
+			m.shellInput.Blur()
+			return m, nil
+		case key.Matches(msg, m.keys.JumpAccept):
+			m.overlay = NoOverlay
+			m.shellInput.Blur()
+			cmdline := strings.TrimSpace(m.shellInput.Value())
+			if cmdline == "" {
+				return m, nil
+			}
+			return m, m.runShell(cmdline)
+		}
+	}
+	var cmd tea.Cmd
+	m.shellInput, cmd = m.shellInput.Update(msg)
+	return m, cmd
+}
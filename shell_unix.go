@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func shellCommand(cmdline string, pause bool) *exec.Cmd {
+	if pause {
+		cmdline += "\nstatus=$?; printf '\\nPress enter to return to nav'; read _; exit $status"
+	}
+	return exec.Command("sh", "-c", cmdline)
+}
@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+func shellQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func shellCommand(cmdline string, pause bool) *exec.Cmd {
+	if pause {
+		cmdline += ` & set "status=!errorlevel!" & pause & exit !status!`
+		return exec.Command("cmd", "/V:ON", "/C", cmdline)
+	}
+	return exec.Command("cmd", "/C", cmdline)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type SortMode int
+
+const (
+	SortName SortMode = iota
+	SortNatural
+	SortSize
+	SortTime
+	SortExt
+	SortType
+)
+
+var sortModeNames = []string{"name", "natural", "size", "time", "extension", "type"}
+
+func (s SortMode) String() string {
+	return sortModeNames[s]
+}
+
+func parseSortMode(s string) (SortMode, error) {
+	for i, name := range sortModeNames {
+		if name == s {
+			return SortMode(i), nil
+		}
+	}
+	return SortName, fmt.Errorf("expected one of %s, got %q", strings.Join(sortModeNames, ", "), s)
+}
+
+type sortSettings struct {
+	mode      SortMode
+	reverse   bool
+	dirsFirst bool
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+func leadingChunk(s string) string {
+	digits := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i]
+}
+
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		ca, cb := leadingChunk(a), leadingChunk(b)
+		if isDigit(ca[0]) && isDigit(cb[0]) {
+			ta, tb := strings.TrimLeft(ca, "0"), strings.TrimLeft(cb, "0")
+			if len(ta) != len(tb) {
+				return len(ta) < len(tb)
+			}
+			if ta != tb {
+				return ta < tb
+			}
+		} else if ca != cb {
+			return ca < cb
+		}
+		a, b = a[len(ca):], b[len(cb):]
+	}
+	return len(a) < len(b)
+}
+
+func typeRank(f os.DirEntry) int {
+	switch {
+	case f.IsDir():
+		return 0
+	case f.Type()&os.ModeSymlink != 0:
+		return 1
+	case f.Type().IsRegular():
+		return 2
+	}
+	return 3
+}
+
+func sortEntries(entries []os.DirEntry, infos map[string]os.FileInfo, s sortSettings) {
+	less := func(a, b os.DirEntry) bool {
+		switch s.mode {
+		case SortNatural:
+			return naturalLess(a.Name(), b.Name())
+		case SortSize:
+			ai, bi := infos[a.Name()], infos[b.Name()]
+			if ai != nil && bi != nil && ai.Size() != bi.Size() {
+				return ai.Size() > bi.Size()
+			}
+		case SortTime:
+			ai, bi := infos[a.Name()], infos[b.Name()]
+			if ai != nil && bi != nil && !ai.ModTime().Equal(bi.ModTime()) {
+				return ai.ModTime().After(bi.ModTime())
+			}
+		case SortExt:
+			ae, be := filepath.Ext(a.Name()), filepath.Ext(b.Name())
+			if ae != be {
+				return ae < be
+			}
+		case SortType:
+			ar, br := typeRank(a), typeRank(b)
+			if ar != br {
+				return ar < br
+			}
+		}
+		return a.Name() < b.Name()
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if s.dirsFirst && a.IsDir() != b.IsDir() {
+			return a.IsDir()
+		}
+		if s.reverse {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
+
+func (m *Model) setSort(s sortSettings) tea.Cmd {
+	m.sort = s
+	m.sortSave[m.currDir] = s
+	if f, ok := m.hoveredFile(); ok {
+		m.lastFile = f.Name()
+	}
+	if m.filterState == FilterApplied {
+		m.filterOff()
+	}
+	return m.readDir(m.currDir)
+}
+
+func (m Model) sortItems() []string {
+	items := make([]string, 0, len(sortModeNames)+2)
+	for i, name := range sortModeNames {
+		marker := "  "
+		if SortMode(i) == m.sort.mode {
+			marker = "* "
+		}
+		items = append(items, marker+"sort by "+name)
+	}
+	items = append(items,
+		fmt.Sprintf("  reverse: %s", onOff(m.sort.reverse)),
+		fmt.Sprintf("  directories first: %s", onOff(m.sort.dirsFirst)),
+	)
+	return items
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func (m Model) sortMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.CloseMenu, m.keys.Sort):
+			m.overlay = NoOverlay
+		case key.Matches(msg, m.keys.Up):
+			m.menu.up()
+		case key.Matches(msg, m.keys.Down):
+			m.menu.down(len(sortModeNames)+2, m.maxHeight)
+		case key.Matches(msg, m.keys.Confirm):
+			s := m.sort
+			switch i := m.menu.idx; {
+			case i < len(sortModeNames):
+				s.mode = SortMode(i)
+				m.overlay = NoOverlay
+			case i == len(sortModeNames):
+				s.reverse = !s.reverse
+			default:
+				s.dirsFirst = !s.dirsFirst
+			}
+			return m, m.setSort(s)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) sortView() string {
+	return m.menu.view(m.sortItems(), m.maxHeight, m.styles)
+}
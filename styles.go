@@ -14,8 +14,14 @@ type Styles struct {
 	PathEnd         lipgloss.Style
 	Filter          lipgloss.Style
 	Selected        lipgloss.Style
-	News			lipgloss.Style
+	News            lipgloss.Style
 	EmptyDir        lipgloss.Style
+	Details         lipgloss.Style
+	Preview         lipgloss.Style
+	Match           lipgloss.Style
+	Tab             lipgloss.Style
+	TabActive       lipgloss.Style
+	Pane            lipgloss.Style
 }
 
 func DefaultStyles() Styles {
@@ -35,7 +41,13 @@ func DefaultStylesWithRenderer(r *lipgloss.Renderer) Styles {
 		PathEnd:         r.NewStyle().Bold(true),
 		Filter:          r.NewStyle().Foreground(lipgloss.Color("11")),
 		Selected:        r.NewStyle().Italic(true).Bold(true),
-		News:			 r.NewStyle().Italic(true),
+		News:            r.NewStyle().Italic(true),
 		EmptyDir:        r.NewStyle().Foreground(lipgloss.Color("8")).SetString("Empty"),
+		Details:         r.NewStyle().Foreground(lipgloss.Color("8")),
+		Preview:         r.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).PaddingLeft(1),
+		Match:           r.NewStyle().Foreground(lipgloss.Color("13")).Bold(true),
+		Tab:             r.NewStyle().Foreground(lipgloss.Color("8")),
+		Pane:            r.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).PaddingLeft(1),
+		TabActive:       r.NewStyle().Background(lipgloss.Color("4")).Foreground(lipgloss.Color("0")),
 	}
 }
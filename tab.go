@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type tab struct {
+	files         []os.DirEntry
+	infos         map[string]os.FileInfo
+	linkDirs      map[string]bool
+	currDir       string
+	idx           int
+	min           int
+	max           int
+	lastFile      string
+	cursorSave    map[string]int
+	filter        FilterFunc
+	filterKind    FilterKind
+	filterErr     error
+	filterState   FilterState
+	filteredFiles filteredFiles
+	filterInput   textinput.Model
+	sort          sortSettings
+	sortSave      map[string]sortSettings
+	history       []string
+	histPos       int
+	prevDir       string
+	showHidden    bool
+	id            int
+}
+
+func newTab(dir string, s sortSettings, kind FilterKind, showHidden bool) tab {
+	filterInput := textinput.New()
+	filterInput.Prompt = kind.String() + " /"
+	return tab{
+		currDir:     dir,
+		history:     []string{dir},
+		cursorSave:  make(map[string]int),
+		filter:      filterFuncs[kind],
+		filterKind:  kind,
+		filterState: Unfiltered,
+		filterInput: filterInput,
+		sort:        s,
+		sortSave:    make(map[string]sortSettings),
+		showHidden:  showHidden,
+		id:          nextID(),
+	}
+}
+
+func (m *Model) saveTab() {
+	if f, ok := m.hoveredFile(); ok && m.filterState == Unfiltered {
+		m.lastFile = f.Name()
+	}
+	m.tabs[m.tabIdx] = m.tab
+	m.panes[m.tabIdx] = paneState{pane: m.pane, right: m.paneRight}
+}
+
+func (m *Model) loadTab(i int) tea.Cmd {
+	m.tabIdx = i
+	m.tab = m.tabs[i]
+	m.pane, m.paneRight = m.panes[i].pane, m.panes[i].right
+	m.max = m.min + m.maxHeight
+	if m.dualPane {
+		m.ensurePane()
+		m.pane.max = m.pane.min + m.maxHeight
+	}
+	m.previewPath = ""
+	m.parentCol.loaded = false
+	m.childCol.loaded = false
+	return tea.Batch(m.readDir(m.currDir), m.reloadPane(), recordFrecency(m.currDir))
+}
+
+func (m *Model) newTab() tea.Cmd {
+	m.saveTab()
+	t := newTab(m.currDir, m.sort, m.filterKind, m.showHidden)
+	t.lastFile = m.lastFile
+	m.tabs = append(m.tabs[:m.tabIdx+1], append([]tab{t}, m.tabs[m.tabIdx+1:]...)...)
+	m.panes = append(m.panes[:m.tabIdx+1], append([]paneState{{}}, m.panes[m.tabIdx+1:]...)...)
+	return m.loadTab(m.tabIdx + 1)
+}
+
+func (m *Model) closeTab() tea.Cmd {
+	if len(m.tabs) == 1 {
+		m.news = "Cannot close the last tab"
+		return nil
+	}
+	m.tabs = append(m.tabs[:m.tabIdx], m.tabs[m.tabIdx+1:]...)
+	m.panes = append(m.panes[:m.tabIdx], m.panes[m.tabIdx+1:]...)
+	return m.loadTab(min(m.tabIdx, len(m.tabs)-1))
+}
+
+func (m *Model) switchTab(i int) tea.Cmd {
+	if i < 0 || len(m.tabs) <= i || i == m.tabIdx {
+		return nil
+	}
+	m.saveTab()
+	return m.loadTab(i)
+}
+
+func (m *Model) cycleTab(delta int) tea.Cmd {
+	return m.switchTab((m.tabIdx + delta + len(m.tabs)) % len(m.tabs))
+}
+
+func (m *Model) jumpToTab(k string) tea.Cmd {
+	n, err := strconv.Atoi(k[len(k)-1:])
+	if err != nil || n == 0 {
+		return nil
+	}
+	return m.switchTab(n - 1)
+}
+
+func (m Model) tabBar() string {
+	if len(m.tabs) < 2 {
+		return ""
+	}
+	bar := ""
+	for i, t := range m.tabs {
+		dir := t.currDir
+		if i == m.tabIdx {
+			dir = m.currDir
+		}
+		label := " " + strconv.Itoa(i+1) + " " + filepath.Base(dir) + " "
+		if i == m.tabIdx {
+			bar += m.styles.TabActive.Render(label)
+		} else {
+			bar += m.styles.Tab.Render(label)
+		}
+	}
+	return bar + "\n"
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	trashInfoExt    = ".trashinfo"
+	trashDateLayout = "2006-01-02T15:04:05"
+)
+
+type trashEntry struct {
+	name    string
+	path    string
+	dir     string
+	deleted time.Time
+}
+
+func trashDir() (string, error) {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataDir = filepath.Join(homeDir, ".local", "share")
+	}
+	dir := filepath.Join(dataDir, "Trash")
+	return dir, makeTrashDir(dir)
+}
+
+func makeTrashDir(dir string) error {
+	for _, sub := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func topDir(path string) (string, error) {
+	dir := path
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+		same, err := sameDevice(parent, path)
+		if err != nil {
+			return "", err
+		}
+		if !same {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
+
+func topTrashDirs(top string) []string {
+	uid := strconv.Itoa(os.Getuid())
+	var dirs []string
+	shared := filepath.Join(top, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		dirs = append(dirs, filepath.Join(shared, uid))
+	}
+	return append(dirs, filepath.Join(top, ".Trash-"+uid))
+}
+
+// trashDirFor returns the trash directory for path and the path to record in
+// its .trashinfo, which is relative to the top directory outside the home trash.
+func trashDirFor(path string) (string, string, error) {
+	home, err := trashDir()
+	if err != nil {
+		return "", "", err
+	}
+	same, err := sameDevice(path, home)
+	if err != nil || same {
+		return home, path, err
+	}
+	top, err := topDir(path)
+	if err != nil {
+		return "", "", err
+	}
+	rel, err := filepath.Rel(top, path)
+	if err != nil {
+		return "", "", err
+	}
+	for _, dir := range topTrashDirs(top) {
+		if err = makeTrashDir(dir); err == nil {
+			return dir, rel, nil
+		}
+	}
+	return "", "", fmt.Errorf("cannot create a trash directory in %s: %w", top, err)
+}
+
+func trashInfoPath(dir, name string) string {
+	return filepath.Join(dir, "info", name+trashInfoExt)
+}
+
+func reserveTrashName(dir, path, recorded string) (string, error) {
+	base := filepath.Base(path)
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: recorded}).EscapedPath(), time.Now().Format(trashDateLayout))
+	for i := 0; ; i++ {
+		name := base
+		if 0 < i {
+			name = numberedName(base, i)
+		}
+		if _, err := os.Lstat(filepath.Join(dir, "files", name)); err == nil {
+			continue
+		}
+		f, err := os.OpenFile(trashInfoPath(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = f.WriteString(info)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(trashInfoPath(dir, name))
+			return "", err
+		}
+		return name, nil
+	}
+}
+
+func (c *copier) trash(src string) error {
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	dir, recorded, err := trashDirFor(abs)
+	if err != nil {
+		return err
+	}
+	name, err := reserveTrashName(dir, abs, recorded)
+	if err != nil {
+		return err
+	}
+	if err := c.move(abs, filepath.Join(dir, "files", name)); err != nil {
+		if _, statErr := os.Lstat(abs); statErr == nil {
+			os.Remove(trashInfoPath(dir, name))
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *copier) restore(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	if err := c.move(src, dest); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(src); err == nil {
+		return nil
+	}
+	return os.Remove(trashInfoPath(filepath.Dir(filepath.Dir(src)), filepath.Base(src)))
+}
+
+func purgeTrashed(path string) error {
+	dir := filepath.Dir(filepath.Dir(path))
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	return os.Remove(trashInfoPath(dir, filepath.Base(path)))
+}
+
+func parseTrashInfo(path, top string) (trashEntry, error) {
+	var e trashEntry
+	f, err := os.Open(path)
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+	e.name = strings.TrimSuffix(filepath.Base(path), trashInfoExt)
+	e.dir = filepath.Dir(filepath.Dir(path))
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "Path":
+			p, err := url.PathUnescape(v)
+			if err != nil {
+				return e, err
+			}
+			e.path = p
+			if !filepath.IsAbs(p) {
+				e.path = filepath.Join(top, p)
+			}
+		case "DeletionDate":
+			t, err := time.ParseInLocation(trashDateLayout, v, time.Local)
+			if err == nil {
+				e.deleted = t
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return e, err
+	}
+	if e.path == "" {
+		return e, fmt.Errorf("%s: missing Path", path)
+	}
+	return e, nil
+}
+
+func listTrash() ([]trashEntry, error) {
+	home, err := trashDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := listTrashDir(home, "")
+	errs := []error{err}
+	seen := map[string]bool{home: true}
+	for _, top := range mountPoints() {
+		for _, dir := range topTrashDirs(top) {
+			if seen[dir] {
+				continue
+			}
+			seen[dir] = true
+			if _, err := os.Stat(filepath.Join(dir, "info")); err != nil {
+				continue
+			}
+			dirEntries, err := listTrashDir(dir, top)
+			entries = append(entries, dirEntries...)
+			errs = append(errs, err)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].deleted.After(entries[j].deleted)
+	})
+	return entries, errors.Join(errs...)
+}
+
+func listTrashDir(dir, top string) ([]trashEntry, error) {
+	infos, err := os.ReadDir(filepath.Join(dir, "info"))
+	if err != nil {
+		return nil, err
+	}
+	var entries []trashEntry
+	var errs []error
+	for _, f := range infos {
+		if !strings.HasSuffix(f.Name(), trashInfoExt) {
+			continue
+		}
+		e, err := parseTrashInfo(filepath.Join(dir, "info", f.Name()), top)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, errors.Join(errs...)
+}
+
+func (m Model) selectedOrHovered() []string {
+	if 0 < len(m.selection) {
+		return getSelectedFilePaths(m.selection)
+	}
+	f, ok := m.hoveredFile()
+	if !ok {
+		return nil
+	}
+	return []string{filepath.Join(m.currDir, f.Name())}
+}
+
+func (m *Model) deselect(path string) {
+	dir, name := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	if fileSet, ok := m.selection[dir]; ok {
+		fileSet.Remove(name)
+		if fileSet.Cardinality() == 0 {
+			delete(m.selection, dir)
+		}
+	}
+}
+
+func (m *Model) trashSelection() tea.Cmd {
+	paths := m.selectedOrHovered()
+	if len(paths) == 0 {
+		m.news = "Nothing to trash"
+		return nil
+	}
+	transfers := make([]transfer, len(paths))
+	for i, p := range paths {
+		transfers[i] = transfer{src: p}
+	}
+	m.news = fmt.Sprintf("Trashing %d file(s)", len(paths))
+	cmd := m.startJob(TrashJob, transfers, journalNone)
+	m.jobs[len(m.jobs)-1].deselect = true
+	return cmd
+}
+
+func (m *Model) openTrash() {
+	entries, err := listTrash()
+	m.trashEntries = entries
+	m.overlay = TrashOverlay
+	m.menu.reset()
+	m.trashConfirm = ""
+	if err != nil {
+		m.news = fmt.Sprintf("Trash error: %s", err)
+	}
+}
+
+func (m *Model) reloadTrash() {
+	idx, min := m.menu.idx, m.menu.min
+	m.openTrash()
+	m.menu.idx, m.menu.min = idx, min
+	if len(m.trashEntries) <= m.menu.idx {
+		m.menu.reset()
+	}
+}
+
+func (m Model) trashMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		confirm := m.trashConfirm
+		m.trashConfirm = ""
+		hasEntry := m.menu.idx < len(m.trashEntries)
+		switch {
+		case key.Matches(msg, m.keys.CloseMenu):
+			m.overlay = NoOverlay
+		case key.Matches(msg, m.keys.Up):
+			m.menu.up()
+		case key.Matches(msg, m.keys.Down):
+			m.menu.down(len(m.trashEntries), m.maxHeight)
+		case key.Matches(msg, m.keys.TrashRestore) && hasEntry:
+			e := m.trashEntries[m.menu.idx]
+			m.news = fmt.Sprintf("Restoring %s", e.path)
+			return m, m.startJob(RestoreJob, []transfer{{src: filepath.Join(e.dir, "files", e.name), dest: e.path}}, journalNone)
+		case key.Matches(msg, m.keys.TrashDelete) && hasEntry:
+			e := m.trashEntries[m.menu.idx]
+			if confirm != "delete" {
+				m.trashConfirm = "delete"
+				m.news = fmt.Sprintf("Press %s again to permanently delete %s", m.keys.TrashDelete.Help().Key, e.name)
+				return m, nil
+			}
+			return m, m.startJob(PurgeJob, []transfer{{src: filepath.Join(e.dir, "files", e.name)}}, journalNone)
+		case key.Matches(msg, m.keys.TrashEmpty) && 0 < len(m.trashEntries):
+			if confirm != "empty" {
+				m.trashConfirm = "empty"
+				m.news = fmt.Sprintf("Press %s again to permanently delete %d file(s)", m.keys.TrashEmpty.Help().Key, len(m.trashEntries))
+				return m, nil
+			}
+			transfers := make([]transfer, len(m.trashEntries))
+			for i, e := range m.trashEntries {
+				transfers[i] = transfer{src: filepath.Join(e.dir, "files", e.name)}
+			}
+			return m, m.startJob(PurgeJob, transfers, journalNone)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) trashView() string {
+	if len(m.trashEntries) == 0 {
+		return m.styles.EmptyDir.Render("Trash is empty") + "\n"
+	}
+	items := make([]string, len(m.trashEntries))
+	for i, e := range m.trashEntries {
+		items[i] = fmt.Sprintf("%s  %s", e.deleted.Format("2006-01-02 15:04"), e.path)
+	}
+	return m.menu.view(items, m.maxHeight, m.styles)
+}
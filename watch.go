@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	watchDebounce = 200 * time.Millisecond
+	watchMaxWait  = time.Second
+)
+
+type dirChangedMsg struct {
+	paths   []string
+	dropped bool
+}
+
+func waitForChange(w *watcher) tea.Cmd {
+	return func() tea.Msg {
+		return <-w.changes
+	}
+}
+
+func (m Model) watchedDirs() []string {
+	var dirs []string
+	if abs, err := filepath.Abs(m.currDir); err == nil {
+		dirs = append(dirs, abs)
+	}
+	if m.millerColumns {
+		for _, p := range []string{m.parentCol.path, m.childCol.path} {
+			if p != "" {
+				dirs = append(dirs, p)
+			}
+		}
+	}
+	if m.dualPane {
+		dirs = append(dirs, m.pane.currDir)
+	}
+	if m.showPreview && m.previewPath != "" && m.previewLines != nil {
+		if f, ok := m.hoveredFile(); ok && f.IsDir() {
+			dirs = append(dirs, m.previewPath)
+		}
+	}
+	return dirs
+}
+
+func (m *Model) updateWatches() tea.Cmd {
+	if !m.watch {
+		return nil
+	}
+	var cmd tea.Cmd
+	if m.watcher == nil {
+		w, err := newWatcher()
+		if err != nil {
+			m.watch = false
+			return nil
+		}
+		m.watcher = w
+		cmd = waitForChange(w)
+	}
+	dirs := m.watchedDirs()
+	if key := strings.Join(dirs, "\x00"); key != m.watchKey && m.watcher.watch(dirs) {
+		m.watchKey = key
+	}
+	return cmd
+}
+
+func (m *Model) handleDirChanged(msg dirChangedMsg) tea.Cmd {
+	cmds := []tea.Cmd{waitForChange(m.watcher)}
+	if msg.dropped {
+		m.watchKey = ""
+	}
+	currDir, _ := filepath.Abs(m.currDir)
+	for _, p := range msg.paths {
+		switch p {
+		case currDir:
+			if f, ok := m.hoveredFile(); ok && m.filterState == Unfiltered {
+				m.lastFile = f.Name()
+			}
+			cmds = append(cmds, m.readDir(m.currDir))
+		case m.parentCol.path:
+			m.parentCol.loaded = false
+		case m.childCol.path:
+			m.childCol.loaded = false
+		}
+		if m.dualPane && p == m.pane.currDir {
+			cmds = append(cmds, m.reloadPane())
+		}
+		if p == m.previewPath {
+			m.previewPath = ""
+		}
+	}
+	return tea.Batch(cmds...)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const watchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF | unix.IN_ONLYDIR
+
+type watcher struct {
+	fd      int
+	file    *os.File
+	changes chan dirChangedMsg
+	mu      sync.Mutex
+	wds     map[int]string
+	paths   map[string]int
+	pending map[string]bool
+	dropped bool
+	since   time.Time
+	timer   *time.Timer
+}
+
+func newWatcher() (*watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	w := &watcher{
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		changes: make(chan dirChangedMsg, 1),
+		wds:     make(map[int]string),
+		paths:   make(map[string]int),
+		pending: make(map[string]bool),
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *watcher) watch(paths []string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	want := make(map[string]bool)
+	for _, p := range paths {
+		want[p] = true
+	}
+	for p, wd := range w.paths {
+		if !want[p] {
+			unix.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.paths, p)
+			delete(w.wds, wd)
+		}
+	}
+	ok := true
+	for p := range want {
+		if _, watched := w.paths[p]; watched {
+			continue
+		}
+		wd, err := unix.InotifyAddWatch(w.fd, p, watchMask)
+		if err != nil {
+			ok = false
+			continue
+		}
+		w.paths[p] = wd
+		w.wds[wd] = p
+	}
+	return ok
+}
+
+func (w *watcher) read() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		w.mu.Lock()
+		burst := len(w.pending) == 0
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			if p, ok := w.wds[int(ev.Wd)]; ok {
+				w.pending[p] = true
+			}
+			if ev.Mask&unix.IN_IGNORED != 0 {
+				if p, ok := w.wds[int(ev.Wd)]; ok {
+					delete(w.paths, p)
+					delete(w.wds, int(ev.Wd))
+					w.dropped = true
+				}
+			}
+			off += unix.SizeofInotifyEvent + int(ev.Len)
+		}
+		if 0 < len(w.pending) {
+			if burst {
+				w.since = time.Now()
+			}
+			switch {
+			case w.timer == nil:
+				w.timer = time.AfterFunc(watchDebounce, w.flush)
+			case burst || time.Since(w.since) < watchMaxWait:
+				w.timer.Reset(watchDebounce)
+			}
+		}
+		w.mu.Unlock()
+	}
+}
+
+func (w *watcher) flush() {
+	w.mu.Lock()
+	var paths []string
+	for p := range w.pending {
+		paths = append(paths, p)
+	}
+	dropped := w.dropped
+	w.pending = make(map[string]bool)
+	w.dropped = false
+	w.mu.Unlock()
+	if 0 < len(paths) {
+		w.changes <- dirChangedMsg{paths: paths, dropped: dropped}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "errors"
+
+type watcher struct {
+	changes chan dirChangedMsg
+}
+
+func newWatcher() (*watcher, error) {
+	return nil, errors.New("directory watching is only supported on Linux")
+}
+
+func (w *watcher) watch(paths []string) bool {
+	return false
+}